@@ -2,17 +2,22 @@ package collector
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
 	"github.com/bitly/go-simplejson"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // 这三个常量用于给每个 Metrics 名字添加前缀
@@ -41,7 +46,8 @@ func GetToken(opts *HWObsOpts) (token string, err error) {
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
 	// 发送 Request 并获取 Response
-	resp, err := (&http.Client{Transport: ts}).Do(req)
+	// Token 在抓取时才获取，需要与其他请求一样受超时时间限制，避免目标无法连接时抓取一直阻塞
+	resp, err := (&http.Client{Transport: ts, Timeout: opts.Timeout}).Do(req)
 	if err != nil {
 		return
 	}
@@ -74,22 +80,28 @@ func GetToken(opts *HWObsOpts) (token string, err error) {
 // HWObsClient 连接 HWObs 所需信息。实现了 CommonClient 接口
 type HWObsClient struct {
 	Client *http.Client
-	Token  string
 	Opts   *HWObsOpts
+
+	// Scrape、健康检查的后台 Ping 以及状态页会并发读写 Token，需要通过 currentToken 与 refreshToken 访问
+	tokenMu sync.RWMutex
+	token   string
+	// tokenUpdatedAt 最近一次成功获取 Token 的时间
+	tokenUpdatedAt time.Time
 }
 
-// NewHWObsClient 实例化 HWObs 客户端
-func NewHWObsClient(opts *HWObsOpts) *HWObsClient {
+// NewHWObsClient 实例化 HWObs 客户端。
+// 实例化时不会获取 Token，第一次抓取时由 Ping 获取，这样多目标模式中单个目标无法连接时只会体现在该目标的 up 上
+func NewHWObsClient(opts *HWObsOpts) (*HWObsClient, error) {
 	uri := opts.URL
 	if !strings.Contains(uri, "://") {
 		uri = "http://" + uri
 	}
 	u, err := url.Parse(uri)
 	if err != nil {
-		panic(fmt.Sprintf("invalid HWObs URL: %s", err))
+		return nil, fmt.Errorf("invalid HWObs URL: %w", err)
 	}
 	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
-		panic(fmt.Sprintf("invalid HWObs URL: %s", uri))
+		return nil, fmt.Errorf("invalid HWObs URL: %s", uri)
 	}
 
 	// ######## 配置 http.Client 的信息 ########
@@ -113,35 +125,37 @@ func NewHWObsClient(opts *HWObsOpts) *HWObsClient {
 	}
 	// ######## 配置 http.Client 的信息结束 ########
 
-	// 启动时获取一次 Token，若获取 Token 失败直接 panic
-	token, err := GetToken(opts)
-	if err != nil {
-		panic(err)
-	}
-
 	return &HWObsClient{
-		Opts:  opts,
-		Token: token,
+		Opts: opts,
 		Client: &http.Client{
 			Timeout:   opts.Timeout,
 			Transport: transport,
 		},
-	}
+	}, nil
 }
 
 // Request 建立与 HWObs 的连接，并返回 Response Body
 func (c *HWObsClient) Request(method string, endpoint string, reqBody io.Reader) (body []byte, err error) {
+	return c.RequestWithContext(context.Background(), method, endpoint, reqBody)
+}
+
+// RequestWithContext 与 Request 相同，每次请求都会作为 ctx 中 Trace 的一个子 Span。实现了 ContextClient 接口
+func (c *HWObsClient) RequestWithContext(ctx context.Context, method string, endpoint string, reqBody io.Reader) (body []byte, err error) {
+	ctx, span := scraper.Tracer().Start(ctx, method+" "+endpoint, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { scraper.EndSpan(span, err) }()
+
 	// 根据认证信息及 endpoint 参数，创建与 HWObs 的连接，并返回 Body 给每个 Metric 采集器
 	url := c.Opts.URL + endpoint
-	logrus.Debugf("request url %s", url)
+	scraper.Logger(ctx).Debugf("request url %s", url)
+	span.SetAttributes(attribute.String("http.method", method), attribute.String("http.url", url))
 
 	// 创建一个新的 Request
 	// req, err := http.NewRequest("GET", url, nil)
-	req, err := http.NewRequest(method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("X-Auth-Token", c.Token)
+	req.Header.Set("X-Auth-Token", c.currentToken())
 
 	// 根据新建立的 Request，发起请求，并获取 Response
 	resp, err := c.Client.Do(req)
@@ -149,6 +163,7 @@ func (c *HWObsClient) Request(method string, endpoint string, reqBody io.Reader)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("error handling request for %s http-statuscode: %s", endpoint, resp.Status)
@@ -168,15 +183,15 @@ func (c *HWObsClient) Request(method string, endpoint string, reqBody io.Reader)
 func (c *HWObsClient) Ping() (b bool, err error) {
 	logrus.Debugf("每次从 HWObs 并发抓取指标之前，先检查一下目标状态")
 	// 判断是否有 Token
-	if c.Token == "" {
+	token := c.currentToken()
+	if token == "" {
 		logrus.Debugf("Token 为空，开始尝试获取 Token")
-		c.Token, err = GetToken(c.Opts)
-		if err == nil {
-			return true, nil
+		if err = c.refreshToken(); err != nil {
+			return false, err
 		}
-		return false, err
+		return true, nil
 	}
-	logrus.Debugf("HWObs Token 为: %s", c.Token)
+	logrus.Debugf("HWObs Token 为: %s", token)
 
 	// 使用 Token 发起健康检查请求，并获取响应体，以进行下一步判断处理
 	logrus.Debugf("Ping Request url %s", c.Opts.URL+"/dsware/service/managerstatus")
@@ -185,7 +200,7 @@ func (c *HWObsClient) Ping() (b bool, err error) {
 		return false, err
 	}
 
-	req.Header.Set("X-Auth-Token", c.Token)
+	req.Header.Set("X-Auth-Token", token)
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
@@ -207,8 +222,7 @@ func (c *HWObsClient) Ping() (b bool, err error) {
 	if result, err := jsonRespBody.Get("result").Int(); err != nil || result != 0 {
 		logrus.Error("Ping 检查失败，原因:", jsonRespBody.Get("description").MustString())
 		logrus.Error("尝试重新获取 Token......")
-		c.Token, err = GetToken(c.Opts)
-		if err == nil {
+		if err = c.refreshToken(); err == nil {
 			return true, nil
 		}
 		logrus.Error("重新获取 Token 失败")
@@ -222,6 +236,37 @@ func (c *HWObsClient) GetConcurrency() int {
 	return c.Opts.Concurrency
 }
 
+// TokenStatus 返回 Token 的状态，不包含 Token 本身。实现了 TokenReporter 接口
+func (c *HWObsClient) TokenStatus() scraper.TokenStatus {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	if c.token == "" {
+		return scraper.TokenStatus{}
+	}
+	updatedAt := c.tokenUpdatedAt
+	return scraper.TokenStatus{Present: true, UpdatedAt: &updatedAt}
+}
+
+// currentToken 返回当前使用的 Token，可以并发调用
+func (c *HWObsClient) currentToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+
+// refreshToken 重新获取 Token，获取成功后替换当前使用的 Token，可以并发调用
+func (c *HWObsClient) refreshToken() error {
+	token, err := GetToken(c.Opts)
+	if err != nil {
+		return err
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = token
+	c.tokenUpdatedAt = time.Now()
+	return nil
+}
+
 // HWObsOpts 登录 HWObs 所需属性
 type HWObsOpts struct {
 	URL         string
@@ -242,3 +287,16 @@ func (o *HWObsOpts) AddFlag() {
 	pflag.DurationVar(&o.Timeout, "time-out", time.Millisecond*6000, "Timeout on HTTP requests to the HWObs API.")
 	pflag.BoolVar(&o.Insecure, "insecure", true, "Disable TLS host verification.")
 }
+
+// ForTarget 返回多目标模式中连接指定目标所需的选项，目标中未设置的认证信息使用 o 中的值
+func (o *HWObsOpts) ForTarget(t *scraper.TargetConfig) *HWObsOpts {
+	targetOpts := *o
+	targetOpts.URL = t.URL
+	if t.Username != "" {
+		targetOpts.Username = t.Username
+	}
+	if t.Password != "" {
+		targetOpts.Password = t.Password
+	}
+	return &targetOpts
+}
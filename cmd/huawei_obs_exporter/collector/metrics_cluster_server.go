@@ -8,18 +8,20 @@ import (
 )
 
 var (
-	_ scraper.CommonScraper = ScrapeCluster{}
+	_ scraper.DescribableScraper = ScrapeCluster{}
 
-	clusterServerCount = prometheus.NewDesc(
+	clusterServerCount = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_server_count"),
 		"集群中节点总数",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
 
-	clusterServerStatus = prometheus.NewDesc(
+	clusterServerStatus = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_server_status"),
 		"集群中节点状态",
-		[]string{"name", "serial_number", "management_ip"}, nil,
+		prometheus.GaugeValue,
+		[]string{"name", "serial_number", "management_ip"},
 	)
 )
 
@@ -36,6 +38,12 @@ func (ScrapeCluster) Help() string {
 	return "HWObs Cluster Server info"
 }
 
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+func (ScrapeCluster) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterServerCount
+	ch <- clusterServerStatus
+}
+
 // Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 HWObs 集群信息的具体行为。
 func (ScrapeCluster) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
 	var (
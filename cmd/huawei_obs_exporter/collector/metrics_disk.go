@@ -10,18 +10,20 @@ import (
 )
 
 var (
-	_ scraper.CommonScraper = ScrapeDisk{}
+	_ scraper.DescribableScraper = ScrapeDisk{}
 
-	diskCount = prometheus.NewDesc(
+	diskCount = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "disk_count"),
 		"集群中磁盘总数",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
 
-	diskStatus = prometheus.NewDesc(
+	diskStatus = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "disk_status"),
 		"集群中磁盘状态",
-		[]string{"disk_role", "disk_slot", "disk_type", "node_ip"}, nil,
+		prometheus.GaugeValue,
+		[]string{"disk_role", "disk_slot", "disk_type", "node_ip"},
 	)
 )
 
@@ -38,6 +40,12 @@ func (ScrapeDisk) Help() string {
 	return "HWObs Cluster Disk info"
 }
 
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+func (ScrapeDisk) Describe(ch chan<- *prometheus.Desc) {
+	ch <- diskCount
+	ch <- diskStatus
+}
+
 // Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 HWObs 集群信息的具体行为。
 func (ScrapeDisk) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
 	var (
@@ -23,43 +23,50 @@ const (
 )
 
 var (
-	_ scraper.CommonScraper = ScrapePerformanceData{}
+	_ scraper.DescribableScraper = ScrapePerformanceData{}
 
-	clusterDeleteRequestPerSecond = prometheus.NewDesc(
+	clusterDeleteRequestPerSecond = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_delete_request_per_second"),
 		"集群 DELETE 请求次数",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
-	clusterGetRequestPerSecond = prometheus.NewDesc(
+	clusterGetRequestPerSecond = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_get_request_per_second"),
 		"集群 GET 请求次数",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
-	clusterPutRequestPerSecond = prometheus.NewDesc(
+	clusterPutRequestPerSecond = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_put_request_per_second"),
 		"集群 PUT 请求次数",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
-	clusterPostRequestPerSecond = prometheus.NewDesc(
+	clusterPostRequestPerSecond = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_post_request_per_second"),
 		"集群 POST 请求次数",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
 
-	clusterReadBandwidth = prometheus.NewDesc(
+	clusterReadBandwidth = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_read_bandwidth"),
 		"集群读带宽,KiB/s",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
-	clusterWriteBandwidth = prometheus.NewDesc(
+	clusterWriteBandwidth = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_write_bandwidth"),
 		"集群写带宽,KiB/s",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
-	clusterTotalBandwidth = prometheus.NewDesc(
+	clusterTotalBandwidth = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "cluster_total_bandwidth"),
 		"集群总带宽,KiB/s",
-		[]string{}, nil,
+		prometheus.GaugeValue,
+		nil,
 	)
 )
 
@@ -76,6 +83,17 @@ func (ScrapePerformanceData) Help() string {
 	return "HWObs Performance Data"
 }
 
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+func (ScrapePerformanceData) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterDeleteRequestPerSecond
+	ch <- clusterGetRequestPerSecond
+	ch <- clusterPutRequestPerSecond
+	ch <- clusterPostRequestPerSecond
+	ch <- clusterReadBandwidth
+	ch <- clusterWriteBandwidth
+	ch <- clusterTotalBandwidth
+}
+
 // Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 HWObs 集群信息的具体行为。
 func (ScrapePerformanceData) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
 	url := "/api/v2/pms/performance_data"
@@ -128,31 +146,61 @@ func (ScrapePerformanceData) Scrape(client scraper.CommonClient, ch chan<- prome
 
 	logrus.Debugf("性能数据响应信息：%v", p)
 
+	// 性能数据是上游按照固定周期统计的，使用采样点的时间戳作为 Metric 的时间戳，
+	// 是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
 	// 集群 DELETE 请求次数
-	deleteRequestPerSecond, _ := strconv.ParseFloat(p[DELETERequestPerSecond].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterDeleteRequestPerSecond, prometheus.GaugeValue, deleteRequestPerSecond)
+	if m := performanceMetric(clusterDeleteRequestPerSecond, p[DELETERequestPerSecond]); m != nil {
+		ch <- m
+	}
 	// 集群 GET 请求次数
-	getRequestPerSecond, _ := strconv.ParseFloat(p[GETRequestPerSecond].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterGetRequestPerSecond, prometheus.GaugeValue, getRequestPerSecond)
+	if m := performanceMetric(clusterGetRequestPerSecond, p[GETRequestPerSecond]); m != nil {
+		ch <- m
+	}
 	// 集群 PUT 请求次数
-	putRequestPerSecond, _ := strconv.ParseFloat(p[PUTRequestPerSecond].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterPutRequestPerSecond, prometheus.GaugeValue, putRequestPerSecond)
+	if m := performanceMetric(clusterPutRequestPerSecond, p[PUTRequestPerSecond]); m != nil {
+		ch <- m
+	}
 	// 集群 POST 请求次数
-	postRequestPerSecond, _ := strconv.ParseFloat(p[POSTRequestPerSecond].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterPostRequestPerSecond, prometheus.GaugeValue, postRequestPerSecond)
+	if m := performanceMetric(clusterPostRequestPerSecond, p[POSTRequestPerSecond]); m != nil {
+		ch <- m
+	}
 	// 集群读带宽
-	readBandwidth, _ := strconv.ParseFloat(p[ReadBandwidth].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterReadBandwidth, prometheus.GaugeValue, readBandwidth)
+	if m := performanceMetric(clusterReadBandwidth, p[ReadBandwidth]); m != nil {
+		ch <- m
+	}
 	// 集群写带宽
-	writeBandwidth, _ := strconv.ParseFloat(p[WriteBandwidth].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterWriteBandwidth, prometheus.GaugeValue, writeBandwidth)
+	if m := performanceMetric(clusterWriteBandwidth, p[WriteBandwidth]); m != nil {
+		ch <- m
+	}
 	// 集群总带宽
-	totalBandwidth, _ := strconv.ParseFloat(p[TotalBandwidth].IndicatorValues[0], 64)
-	ch <- prometheus.MustNewConstMetric(clusterTotalBandwidth, prometheus.GaugeValue, totalBandwidth)
+	if m := performanceMetric(clusterTotalBandwidth, p[TotalBandwidth]); m != nil {
+		ch <- m
+	}
 
 	return nil
 }
 
+// performanceMetric 使用性能数据中最新的采样点生成带有时间戳的 Metric，没有采样点或值无法解析时返回 nil。
+// indicator_values 按时间先后排列，所以使用最后一个元素而不是第一个；timestamp 是以秒为单位的 Unix 时间，
+// 与 indicator_values 的数量不一致时无法确定采样时间，此时生成不带时间戳的 Metric
+func performanceMetric(desc *prometheus.Desc, d pData) prometheus.Metric {
+	n := len(d.IndicatorValues)
+	if n == 0 {
+		logrus.WithField("indicator", d.Indicator).Debug("性能数据中没有采样点")
+		return nil
+	}
+	value, err := strconv.ParseFloat(d.IndicatorValues[n-1], 64)
+	if err != nil {
+		logrus.WithField("indicator", d.Indicator).Errorf("无法解析性能数据: %v", err)
+		return nil
+	}
+	m := prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	if len(d.Timestamp) == n {
+		return prometheus.NewMetricWithTimestamp(time.Unix(int64(d.Timestamp[n-1]), 0), m)
+	}
+	return m
+}
+
 // performanceData 性能数据
 type performanceData struct {
 	Data   []pData `json:"data"`
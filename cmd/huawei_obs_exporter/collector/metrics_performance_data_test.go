@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPerformanceMetric(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      pData
+		wantNil   bool
+		value     float64
+		timestamp *int64
+	}{
+		{
+			name: "latest sample with timestamp in seconds",
+			data: pData{IndicatorValues: []string{"1.5", "2.5", "3.5"}, Timestamp: []int{1700000000, 1700000060, 1700000120}},
+			// 使用最后一个采样点，时间戳从秒转换为毫秒
+			value:     3.5,
+			timestamp: int64Ptr(1700000120000),
+		},
+		{
+			name:  "timestamps do not match values",
+			data:  pData{IndicatorValues: []string{"1", "2"}, Timestamp: []int{1700000000}},
+			value: 2,
+		},
+		{
+			name:    "no samples",
+			data:    pData{},
+			wantNil: true,
+		},
+		{
+			name:    "invalid value",
+			data:    pData{IndicatorValues: []string{"1", "--"}, Timestamp: []int{1700000000, 1700000060}},
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := performanceMetric(clusterReadBandwidth, tt.data)
+			if tt.wantNil {
+				if m != nil {
+					t.Errorf("performanceMetric() = %v, want nil", m)
+				}
+				return
+			}
+			if m == nil {
+				t.Fatal("performanceMetric() = nil")
+			}
+			var pb dto.Metric
+			if err := m.Write(&pb); err != nil {
+				t.Fatal(err)
+			}
+			if pb.GetGauge().GetValue() != tt.value {
+				t.Errorf("value = %v, want %v", pb.GetGauge().GetValue(), tt.value)
+			}
+			if (pb.TimestampMs == nil) != (tt.timestamp == nil) || (tt.timestamp != nil && pb.GetTimestampMs() != *tt.timestamp) {
+				t.Errorf("timestamp = %v, want %v", pb.TimestampMs, tt.timestamp)
+			}
+			if m.Desc() != clusterReadBandwidth {
+				t.Errorf("desc = %v, want %v", m.Desc(), clusterReadBandwidth)
+			}
+		})
+	}
+}
+
+func int64Ptr(i int64) *int64 { return &i }
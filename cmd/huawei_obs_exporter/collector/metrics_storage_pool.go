@@ -10,23 +10,26 @@ import (
 )
 
 var (
-	_ scraper.CommonScraper = ScrapeStoragePool{}
+	_ scraper.DescribableScraper = ScrapeStoragePool{}
 
-	storagePoolStatus = prometheus.NewDesc(
+	storagePoolStatus = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "storage_pool_status"),
 		"存储池状态,0：正常,1：故障,2：写保护,3：停止,4：故障且写保护,5：数据迁移,7：降级,8：数据重构",
-		[]string{"pool_id"}, nil,
+		prometheus.GaugeValue,
+		[]string{"pool_id"},
 	)
 
-	storagePoolTotalCapacity = prometheus.NewDesc(
+	storagePoolTotalCapacity = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "storage_pool_total_capacity"),
 		"存储池总容量,MiB",
-		[]string{"pool_id"}, nil,
+		prometheus.GaugeValue,
+		[]string{"pool_id"},
 	)
-	storagePoolUsedCapacity = prometheus.NewDesc(
+	storagePoolUsedCapacity = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "storage_pool_used_capacity"),
 		"存储池已用容量,MiB",
-		[]string{"pool_id"}, nil,
+		prometheus.GaugeValue,
+		[]string{"pool_id"},
 	)
 )
 
@@ -43,6 +46,13 @@ func (ScrapeStoragePool) Help() string {
 	return "HWObs Storage Pool info"
 }
 
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+func (ScrapeStoragePool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- storagePoolStatus
+	ch <- storagePoolTotalCapacity
+	ch <- storagePoolUsedCapacity
+}
+
 // Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 HWObs 集群信息的具体行为。
 func (ScrapeStoragePool) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
 	var (
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/cmd/huawei_obs_exporter/collector"
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+)
+
+// TestMetricsCatalog 检查提交的 METRICS.md 与所有 Scraper 声明的 Metric 一致，不一致时需要执行 go generate 重新生成
+func TestMetricsCatalog(t *testing.T) {
+	scraper.Namespace = collector.Namespace
+	allScrapers := []scraper.CommonScraper{}
+	for scraper := range scrapers {
+		allScrapers = append(allScrapers, scraper)
+	}
+	var got bytes.Buffer
+	if err := scraper.WriteCatalog(&got, "markdown", scraper.NewCatalog(allScrapers)); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile("METRICS.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("METRICS.md is out of date, run go generate ./cmd/huawei_obs_exporter/ to update it.\ngot:\n%s", got.String())
+	}
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 上游数据中的单位与 Metric 使用的基本单位之间的换算比例。
+// 微秒与百分比使用除法换算，避免 e.g. 800 * 1e-6 得到 0.0007999999999999999
+const (
+	kibibyte              = 1024
+	microsecondsPerSecond = 1e6
+	percentPerRatio       = 100
+)
+
+// pageLimit 分页获取资源列表时每一页的数量
+const pageLimit = 100
+
+// listAll 分页获取 endpoint 中的所有资源，key 为响应体中资源列表所在的字段，e.g. /api/v1/pools 的 pools。
+// 某一页不足 pageLimit 时停止；响应体中包含 paging 时，获取到的资源达到 paging.total_count 同样停止。
+// 没有 paging 的响应无法得知资源的总数，只能继续请求下一页，直到某一页不足 pageLimit
+func listAll[T any](client scraper.CommonClient, endpoint string, key string) ([]T, error) {
+	// endpoint 中可能已经包含查询参数，e.g. /api/v1/alerts?resolved=false
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+
+	var items []T
+	for offset := 0; ; {
+		respBody, err := client.Request("GET", fmt.Sprintf("%s%slimit=%d&offset=%d", endpoint, sep, pageLimit, offset), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			page   map[string]json.RawMessage
+			paging *Paging
+			list   []T
+		)
+		if err = json.Unmarshal(respBody, &page); err != nil {
+			return nil, err
+		}
+		if raw, ok := page[key]; ok {
+			if err = json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("decoding %s of %s: %w", key, endpoint, err)
+			}
+		}
+		if raw, ok := page["paging"]; ok {
+			if err = json.Unmarshal(raw, &paging); err != nil {
+				return nil, fmt.Errorf("decoding paging of %s: %w", endpoint, err)
+			}
+		}
+
+		items = append(items, list...)
+		offset += len(list)
+		if len(list) < pageLimit || (paging != nil && offset >= paging.TotalCount) {
+			return items, nil
+		}
+	}
+}
+
+// sample 是 Xsky 各种资源中的采样点
+type sample interface {
+	created() time.Time
+}
+
+// latestSample 返回创建时间最新的采样点，samples 为空时 ok 为 false
+func latestSample[T sample](samples []T) (latest T, ok bool) {
+	for i, s := range samples {
+		if i == 0 || s.created().After(latest.created()) {
+			latest = s
+		}
+	}
+	return latest, len(samples) > 0
+}
+
+// stateSet 以 state-set 的形式发送状态：states 中的每个状态都发送一个 Metric，当前状态的值为 1，其余为 0。
+// 状态作为 desc 的最后一个标签。current 不在 states 中时同样会发送，以免上游新增的状态被忽略
+func stateSet(ch chan<- prometheus.Metric, desc *prometheus.Desc, states []string, current string, labels ...string) {
+	known := false
+	for _, state := range states {
+		value := 0.0
+		if state == current {
+			value, known = 1, true
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels, state)...)
+	}
+	if !known && current != "" {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, append(labels, current)...)
+	}
+}
+
+// boolToFloat64 将布尔值转换为 Metric 的值，true 为 1，false 为 0
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package collector
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLatestSample(t *testing.T) {
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	t3 := t2.Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		samples []Samples
+		want    time.Time
+		ok      bool
+	}{
+		{name: "empty"},
+		{name: "single", samples: []Samples{{Create: t1}}, want: t1, ok: true},
+		// 上游返回的采样点不一定按时间排序，不能直接使用第一个或最后一个
+		{name: "unordered", samples: []Samples{{Create: t2}, {Create: t3}, {Create: t1}}, want: t3, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := latestSample(tt.samples)
+			if ok != tt.ok || !got.Create.Equal(tt.want) {
+				t.Errorf("latestSample() = %v, %v, want %v, %v", got.Create, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// pagedClient 是模拟 Xsky 分页接口的 CommonClient，items 为资源的总数，totalCount 为响应中 paging.total_count 的值，
+// noPaging 为 true 时响应中不包含 paging
+type pagedClient struct {
+	items      int
+	totalCount int
+	noPaging   bool
+	requests   []string
+}
+
+func (c *pagedClient) Request(method string, endpoint string, reqBody io.Reader) ([]byte, error) {
+	c.requests = append(c.requests, endpoint)
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	limit, _ := strconv.Atoi(u.Query().Get("limit"))
+	offset, _ := strconv.Atoi(u.Query().Get("offset"))
+
+	items := []map[string]int{}
+	for i := offset; i < offset+limit && i < c.items; i++ {
+		items = append(items, map[string]int{"id": i})
+	}
+	resp := map[string]any{"items": items}
+	if !c.noPaging {
+		resp["paging"] = Paging{Count: len(items), Limit: limit, Offset: offset, TotalCount: c.totalCount}
+	}
+	return json.Marshal(resp)
+}
+
+func (c *pagedClient) Ping() (bool, error) { return true, nil }
+
+func (c *pagedClient) GetConcurrency() int { return 1 }
+
+func TestListAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		endpoint   string
+		items      int
+		totalCount int
+		noPaging   bool
+		requests   []string
+	}{
+		{
+			name:       "stops at short page",
+			endpoint:   "/api/v1/disks",
+			items:      150,
+			totalCount: 1000,
+			requests:   []string{"/api/v1/disks?limit=100&offset=0", "/api/v1/disks?limit=100&offset=100"},
+		},
+		{
+			// 资源数量正好是 pageLimit 的整数倍时，不需要再请求一个空页
+			name:       "stops at total count",
+			endpoint:   "/api/v1/disks",
+			items:      200,
+			totalCount: 200,
+			requests:   []string{"/api/v1/disks?limit=100&offset=0", "/api/v1/disks?limit=100&offset=100"},
+		},
+		{
+			name:       "endpoint with query",
+			endpoint:   "/api/v1/alerts?resolved=false",
+			items:      3,
+			totalCount: 3,
+			requests:   []string{"/api/v1/alerts?resolved=false&limit=100&offset=0"},
+		},
+		{
+			// 没有 paging 时无法得知总数，继续请求直到某一页不足 pageLimit
+			name:     "without paging",
+			endpoint: "/api/v1/disks",
+			items:    200,
+			noPaging: true,
+			requests: []string{"/api/v1/disks?limit=100&offset=0", "/api/v1/disks?limit=100&offset=100", "/api/v1/disks?limit=100&offset=200"},
+		},
+		{
+			name:     "empty",
+			endpoint: "/api/v1/disks",
+			requests: []string{"/api/v1/disks?limit=100&offset=0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &pagedClient{items: tt.items, totalCount: tt.totalCount, noPaging: tt.noPaging}
+			got, err := listAll[struct {
+				ID int `json:"id"`
+			}](c, tt.endpoint, "items")
+			if err != nil {
+				t.Fatalf("listAll() = %v", err)
+			}
+			if len(got) != tt.items {
+				t.Errorf("listAll() returned %d items, want %d", len(got), tt.items)
+			}
+			for i, item := range got {
+				if item.ID != i {
+					t.Fatalf("item %d has id %d", i, item.ID)
+				}
+			}
+			if !reflect.DeepEqual(c.requests, tt.requests) {
+				t.Errorf("requests = %v, want %v", c.requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestStateSet(t *testing.T) {
+	desc := prometheus.NewDesc("test_disk_status", "Test disk status.", []string{"disk_id", "status"}, nil)
+	states := []string{"active", "inactive", "error"}
+
+	tests := []struct {
+		name    string
+		current string
+		want    map[string]float64
+	}{
+		{
+			name:    "known state",
+			current: "inactive",
+			want:    map[string]float64{"active": 0, "inactive": 1, "error": 0},
+		},
+		{
+			// 上游新增的状态同样发送，以免被忽略
+			name:    "unknown state",
+			current: "rebuilding",
+			want:    map[string]float64{"active": 0, "inactive": 0, "error": 0, "rebuilding": 1},
+		},
+		{
+			name: "empty state",
+			want: map[string]float64{"active": 0, "inactive": 0, "error": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan prometheus.Metric, len(states)+1)
+			stateSet(ch, desc, states, tt.current, "1")
+			close(ch)
+
+			got := make(map[string]float64)
+			for m := range ch {
+				var pb dto.Metric
+				if err := m.Write(&pb); err != nil {
+					t.Fatal(err)
+				}
+				labels := make(map[string]string)
+				for _, lp := range pb.Label {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				if labels["disk_id"] != "1" {
+					t.Errorf("disk_id = %q, want 1", labels["disk_id"])
+				}
+				got[labels["status"]] = pb.GetGauge().GetValue()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stateSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapeAlert{}
+
+	// 告警已知的级别，每个级别都会产生一个 xsky_alert_active_count，没有告警时值为 0
+	alertSeverities = []string{"info", "warning", "error", "critical"}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取告警相关的数据。
+	// 每个未恢复的告警都是一个序列，告警恢复后该序列不再产生，标签集合固定，以便通过 Prometheus 规则转发或抑制
+	alertActive = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "alert", "active"),
+		"Alert raised by Xsky that is not resolved yet, value is always 1. resource is the type and name of the alerting resource, e.g. disk/node1:sda.",
+		prometheus.GaugeValue,
+		[]string{"alert_id", "type", "severity", "resource"},
+	)
+	alertActiveCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "alert", "active_count"),
+		"Number of alerts raised by Xsky that are not resolved yet, by severity.",
+		prometheus.GaugeValue,
+		[]string{"severity"},
+	)
+)
+
+// ScrapeAlert 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeAlert struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeAlert 结构体实现 Scraper 接口
+func (ScrapeAlert) Name() string {
+	return "alert_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeAlert 结构体实现 Scraper 接口
+func (ScrapeAlert) Help() string {
+	return "Xsky active alerts"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeAlert 结构体实现 DescribableScraper 接口
+func (ScrapeAlert) Describe(ch chan<- *prometheus.Desc) {
+	ch <- alertActive
+	ch <- alertActiveCount
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 告警信息的具体行为。
+// 该方法用于为 ScrapeAlert 结构体实现 Scraper 接口
+func (ScrapeAlert) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	// 只获取未恢复的告警，已恢复的告警不再产生序列
+	alerts, err := listAll[Alerts](client, "/api/v1/alerts?resolved=false", "alerts")
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int, len(alertSeverities))
+	for _, severity := range alertSeverities {
+		counts[severity] = 0
+	}
+	for _, alert := range alerts {
+		// 上游忽略查询参数时也不会发送已恢复的告警
+		if alert.Resolved {
+			continue
+		}
+		counts[alert.Level]++
+		ch <- prometheus.MustNewConstMetric(alertActive, prometheus.GaugeValue, 1,
+			strconv.Itoa(alert.ID), alert.Type, alert.Level, alert.resource(),
+		)
+	}
+	for severity, count := range counts {
+		ch <- prometheus.MustNewConstMetric(alertActiveCount, prometheus.GaugeValue, float64(count), severity)
+	}
+	logrus.Debugf("当前一共有 %v 个未恢复的告警", len(alerts))
+	return nil
+}
+
+// Alerts 是 Xsky 告警相关信息的 Response Body 中 alerts 数组的元素
+type Alerts struct {
+	Create       time.Time `json:"create"`
+	ID           int       `json:"id"`
+	Level        string    `json:"level"`
+	Message      string    `json:"message"`
+	ResourceID   int       `json:"resource_id"`
+	ResourceName string    `json:"resource_name"`
+	ResourceType string    `json:"resource_type"`
+	Resolved     bool      `json:"resolved"`
+	Type         string    `json:"type"`
+	Update       time.Time `json:"update"`
+}
+
+// resource 返回告警资源的类型与名称，e.g. disk/node1:sda
+func (a Alerts) resource() string {
+	if a.ResourceType == "" {
+		return a.ResourceName
+	}
+	return a.ResourceType + "/" + a.ResourceName
+}
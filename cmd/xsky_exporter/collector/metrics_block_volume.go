@@ -0,0 +1,292 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper  = ScrapeBlockVolume{}
+	_ scraper.ConfigurableScraper = ScrapeBlockVolume{}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取块存储卷与快照相关的数据。
+	blockVolumeCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "block_volume_count"),
+		"Total number of block volumes in the cluster, including volumes excluded by filters.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// blockVolumeLabels 是每个卷的 Metric 都具有的标签
+	blockVolumeLabels = []string{"volume_id", "volume_name", "pool_name"}
+
+	// 卷已知的状态，上游返回其他状态时同样会作为一个 state 发送
+	blockVolumeStates = []string{"active", "warning", "error", "offline"}
+
+	blockVolumeStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "status"),
+		"Status of the block volume, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(blockVolumeLabels, "status"),
+	)
+	blockVolumeSizeBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "size_bytes"),
+		"Provisioned size of the block volume in bytes.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeAllocatedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "allocated_bytes"),
+		"Capacity actually allocated to the block volume in the pool in bytes.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	// 以下 Metric 只有卷开启了 QoS 并设置了对应的限制时才会产生
+	blockVolumeQoSIOPSLimit = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "qos_iops_limit"),
+		"Maximum total IOPS allowed by the QoS policy of the block volume, only present when QoS is enabled.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeQoSBytesPerSecondLimit = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "qos_bytes_per_second_limit"),
+		"Maximum total bandwidth in bytes per second allowed by the QoS policy of the block volume, only present when QoS is enabled.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeSnapshots = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "snapshots"),
+		"Number of snapshots of the block volume.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeSnapshotBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "snapshot_bytes"),
+		"Total size of the snapshots of the block volume in bytes.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+
+	// 以下 Metric 来自卷最新的采样点
+	blockVolumeReadIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "read_iops"),
+		"Read operations per second.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeWriteIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "write_iops"),
+		"Write operations per second.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeReadBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "read_bytes_per_second"),
+		"Read bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeWriteBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "write_bytes_per_second"),
+		"Write bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeReadLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "read_latency_seconds"),
+		"Average read latency in seconds.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+	blockVolumeWriteLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "block_volume", "write_latency_seconds"),
+		"Average write latency in seconds.",
+		prometheus.GaugeValue,
+		blockVolumeLabels,
+	)
+)
+
+// ScrapeBlockVolume 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeBlockVolume struct {
+	// poolFilter 与 volumeFilter 分别按存储池名称与卷名称过滤，通过配置文件中该 Scraper 的 filters.pool 与 filters.volume 设置，为 nil 时不过滤
+	poolFilter   *scraper.NameFilter
+	volumeFilter *scraper.NameFilter
+}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeBlockVolume 结构体实现 Scraper 接口
+func (ScrapeBlockVolume) Name() string {
+	return "block_volume_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeBlockVolume 结构体实现 Scraper 接口
+func (ScrapeBlockVolume) Help() string {
+	return "Xsky block volume capacity, QoS, snapshots and performance samples, filterable by pool or volume name in the config file"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeBlockVolume 结构体实现 DescribableScraper 接口
+func (ScrapeBlockVolume) Describe(ch chan<- *prometheus.Desc) {
+	ch <- blockVolumeCount
+	ch <- blockVolumeStatus
+	ch <- blockVolumeSizeBytes
+	ch <- blockVolumeAllocatedBytes
+	ch <- blockVolumeQoSIOPSLimit
+	ch <- blockVolumeQoSBytesPerSecondLimit
+	ch <- blockVolumeSnapshots
+	ch <- blockVolumeSnapshotBytes
+	ch <- blockVolumeReadIOPS
+	ch <- blockVolumeWriteIOPS
+	ch <- blockVolumeReadBytesPerSecond
+	ch <- blockVolumeWriteBytesPerSecond
+	ch <- blockVolumeReadLatencySeconds
+	ch <- blockVolumeWriteLatencySeconds
+}
+
+// Configure 使用配置文件中的 filters.pool 与 filters.volume 按存储池名称与卷名称过滤
+// 该方法用于为 ScrapeBlockVolume 结构体实现 ConfigurableScraper 接口
+func (s ScrapeBlockVolume) Configure(cfg *scraper.ScraperConfig) (scraper.CommonScraper, error) {
+	if err := cfg.CheckFilters("pool", "volume"); err != nil {
+		return nil, err
+	}
+	s.poolFilter = cfg.Filter("pool")
+	s.volumeFilter = cfg.Filter("volume")
+	return s, nil
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 块存储卷信息的具体行为。
+// 该方法用于为 ScrapeBlockVolume 结构体实现 Scraper 接口
+func (s ScrapeBlockVolume) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	// 卷与快照的数量都可能很多，分页获取
+	volumes, err := listAll[BlockVolumes](client, "/api/v1/block-volumes", "block_volumes")
+	if err != nil {
+		return err
+	}
+	snapshots, err := listAll[BlockSnapshots](client, "/api/v1/block-snapshots", "block_snapshots")
+	if err != nil {
+		return err
+	}
+
+	// 按卷汇总快照的数量与大小
+	snapshotCounts := make(map[int]int)
+	snapshotBytes := make(map[int]int64)
+	for _, snapshot := range snapshots {
+		snapshotCounts[snapshot.Volume.ID]++
+		snapshotBytes[snapshot.Volume.ID] += snapshot.Size
+	}
+
+	ch <- prometheus.MustNewConstMetric(blockVolumeCount, prometheus.GaugeValue, float64(len(volumes)))
+	scraped := 0
+	for _, volume := range volumes {
+		if !s.poolFilter.Match(volume.Pool.Name) || !s.volumeFilter.Match(volume.Name) {
+			continue
+		}
+		scraped++
+
+		labels := []string{strconv.Itoa(volume.ID), volume.Name, volume.Pool.Name}
+		scrapeBlockVolumeStatus(volume, labels, ch)
+		ch <- prometheus.MustNewConstMetric(blockVolumeSnapshots, prometheus.GaugeValue, float64(snapshotCounts[volume.ID]), labels...)
+		ch <- prometheus.MustNewConstMetric(blockVolumeSnapshotBytes, prometheus.GaugeValue, float64(snapshotBytes[volume.ID]), labels...)
+		scrapeBlockVolumeSamples(volume, labels, ch)
+	}
+	logrus.Debugf("当前一共有 %v 个卷，%v 个快照，过滤后剩余 %v 个卷", len(volumes), len(snapshots), scraped)
+	return nil
+}
+
+// scrapeBlockVolumeStatus 发送一个卷的状态、容量与 QoS 限制
+func scrapeBlockVolumeStatus(volume BlockVolumes, labels []string, ch chan<- prometheus.Metric) {
+	stateSet(ch, blockVolumeStatus, blockVolumeStates, volume.Status, labels...)
+	ch <- prometheus.MustNewConstMetric(blockVolumeSizeBytes, prometheus.GaugeValue, float64(volume.Size), labels...)
+	ch <- prometheus.MustNewConstMetric(blockVolumeAllocatedBytes, prometheus.GaugeValue, float64(volume.AllocatedSize), labels...)
+
+	if !volume.QosEnabled {
+		return
+	}
+	if volume.Qos.MaxTotalIops > 0 {
+		ch <- prometheus.MustNewConstMetric(blockVolumeQoSIOPSLimit, prometheus.GaugeValue, float64(volume.Qos.MaxTotalIops), labels...)
+	}
+	if volume.Qos.MaxTotalBw > 0 {
+		ch <- prometheus.MustNewConstMetric(blockVolumeQoSBytesPerSecondLimit, prometheus.GaugeValue, float64(volume.Qos.MaxTotalBw), labels...)
+	}
+}
+
+// scrapeBlockVolumeSamples 发送一个卷最新采样点中的性能数据
+func scrapeBlockVolumeSamples(volume BlockVolumes, labels []string, ch chan<- prometheus.Metric) {
+	sample, ok := latestSample(volume.Samples)
+	if !ok {
+		logrus.Debugf("卷 %v 没有采样点", volume.Name)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+	}
+
+	gauge(blockVolumeReadIOPS, float64(sample.ReadIops))
+	gauge(blockVolumeWriteIOPS, float64(sample.WriteIops))
+	gauge(blockVolumeReadBytesPerSecond, float64(sample.ReadBandwidthKbyte)*kibibyte)
+	gauge(blockVolumeWriteBytesPerSecond, float64(sample.WriteBandwidthKbyte)*kibibyte)
+	gauge(blockVolumeReadLatencySeconds, float64(sample.ReadLatencyUs)/microsecondsPerSecond)
+	gauge(blockVolumeWriteLatencySeconds, float64(sample.WriteLatencyUs)/microsecondsPerSecond)
+}
+
+// ResourceRef 是其他资源中对存储池、卷等资源的引用
+type ResourceRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// BlockVolumes 是 Xsky 块存储卷相关信息的 Response Body 中 block_volumes 数组的元素
+type BlockVolumes struct {
+	ActionStatus string `json:"action_status"`
+	// AllocatedSize 与 Size 的单位为 Byte
+	AllocatedSize int64                `json:"allocated_size"`
+	Create        time.Time            `json:"create"`
+	ID            int                  `json:"id"`
+	Name          string               `json:"name"`
+	Pool          ResourceRef          `json:"pool"`
+	Qos           BlockVolumeQos       `json:"qos"`
+	QosEnabled    bool                 `json:"qos_enabled"`
+	Samples       []BlockVolumeSamples `json:"samples"`
+	Size          int64                `json:"size"`
+	Status        string               `json:"status"`
+	Update        time.Time            `json:"update"`
+}
+
+// BlockVolumeQos 是 BlockVolumes 的子集，MaxTotalBw 的单位为 Byte/s，为 0 时表示不限制
+type BlockVolumeQos struct {
+	BurstTotalBw   int64 `json:"burst_total_bw"`
+	BurstTotalIops int64 `json:"burst_total_iops"`
+	MaxTotalBw     int64 `json:"max_total_bw"`
+	MaxTotalIops   int64 `json:"max_total_iops"`
+}
+
+// BlockVolumeSamples 是 BlockVolumes 的子集，一个数组
+type BlockVolumeSamples struct {
+	Create              time.Time `json:"create"`
+	ReadBandwidthKbyte  int64     `json:"read_bandwidth_kbyte"`
+	ReadIops            int       `json:"read_iops"`
+	ReadLatencyUs       int       `json:"read_latency_us"`
+	WriteBandwidthKbyte int64     `json:"write_bandwidth_kbyte"`
+	WriteIops           int       `json:"write_iops"`
+	WriteLatencyUs      int       `json:"write_latency_us"`
+}
+
+func (s BlockVolumeSamples) created() time.Time { return s.Create }
+
+// BlockSnapshots 是 Xsky 块存储快照相关信息的 Response Body 中 block_snapshots 数组的元素
+type BlockSnapshots struct {
+	Create time.Time `json:"create"`
+	ID     int       `json:"id"`
+	Name   string    `json:"name"`
+	// Size 快照的大小，单位为 Byte
+	Size   int64       `json:"size"`
+	Status string      `json:"status"`
+	Volume ResourceRef `json:"volume"`
+}
@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper  = ScrapeBucket{}
+	_ scraper.ConfigurableScraper = ScrapeBucket{}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取对象存储桶相关的数据。
+	bucketCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "bucket_count"),
+		"Total number of object storage buckets in the cluster, including buckets excluded by filters.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// bucketLabels 是每个存储桶的 Metric 都具有的标签
+	bucketLabels = []string{"bucket", "owner", "storage_class"}
+
+	bucketUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "bucket", "used_bytes"),
+		"Size of the objects stored in the bucket in bytes.",
+		prometheus.GaugeValue,
+		bucketLabels,
+	)
+	bucketObjects = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "bucket", "objects"),
+		"Number of objects stored in the bucket.",
+		prometheus.GaugeValue,
+		bucketLabels,
+	)
+	// 以下 Metric 只有存储桶设置了配额时才会产生
+	bucketQuotaBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "bucket", "quota_bytes"),
+		"Maximum size of the bucket in bytes, only present when a size quota is set.",
+		prometheus.GaugeValue,
+		bucketLabels,
+	)
+	bucketQuotaObjects = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "bucket", "quota_objects"),
+		"Maximum number of objects in the bucket, only present when an object quota is set.",
+		prometheus.GaugeValue,
+		bucketLabels,
+	)
+	bucketQuotaUsedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "bucket", "quota_used_ratio"),
+		"Ratio of the size quota of the bucket that is used, only present when a size quota is set.",
+		prometheus.GaugeValue,
+		bucketLabels,
+	)
+)
+
+// ScrapeBucket 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeBucket struct {
+	// filter 按存储桶名称过滤，通过配置文件中该 Scraper 的 filters.bucket 设置，为 nil 时不过滤
+	filter *scraper.NameFilter
+}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeBucket 结构体实现 Scraper 接口
+func (ScrapeBucket) Name() string {
+	return "bucket_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeBucket 结构体实现 Scraper 接口
+func (ScrapeBucket) Help() string {
+	return "Xsky object storage bucket usage and quotas, filterable by bucket name in the config file"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeBucket 结构体实现 DescribableScraper 接口
+func (ScrapeBucket) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bucketCount
+	ch <- bucketUsedBytes
+	ch <- bucketObjects
+	ch <- bucketQuotaBytes
+	ch <- bucketQuotaObjects
+	ch <- bucketQuotaUsedRatio
+}
+
+// Configure 使用配置文件中的 filters.bucket 按存储桶名称过滤
+// 该方法用于为 ScrapeBucket 结构体实现 ConfigurableScraper 接口
+func (s ScrapeBucket) Configure(cfg *scraper.ScraperConfig) (scraper.CommonScraper, error) {
+	if err := cfg.CheckFilters("bucket"); err != nil {
+		return nil, err
+	}
+	s.filter = cfg.Filter("bucket")
+	return s, nil
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 存储桶信息的具体行为。
+// 该方法用于为 ScrapeBucket 结构体实现 Scraper 接口
+func (s ScrapeBucket) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	// 存储桶的数量可能很多，分页获取所有存储桶
+	buckets, err := listAll[Buckets](client, "/api/v1/os/buckets", "os_buckets")
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(bucketCount, prometheus.GaugeValue, float64(len(buckets)))
+	scraped := 0
+	for _, bucket := range buckets {
+		if !s.filter.Match(bucket.Name) {
+			continue
+		}
+		scraped++
+		scrapeBucketUsage(bucket, ch)
+	}
+	logrus.Debugf("当前一共有 %v 个存储桶，过滤后剩余 %v 个", len(buckets), scraped)
+	return nil
+}
+
+// scrapeBucketUsage 发送一个存储桶的配额，以及其最新采样点中的使用量
+func scrapeBucketUsage(bucket Buckets, ch chan<- prometheus.Metric) {
+	labels := []string{bucket.Name, bucket.Owner.Name, bucket.StorageClass}
+	if bucket.QuotaMaxSize > 0 {
+		ch <- prometheus.MustNewConstMetric(bucketQuotaBytes, prometheus.GaugeValue, float64(bucket.QuotaMaxSize), labels...)
+	}
+	if bucket.QuotaMaxObjects > 0 {
+		ch <- prometheus.MustNewConstMetric(bucketQuotaObjects, prometheus.GaugeValue, float64(bucket.QuotaMaxObjects), labels...)
+	}
+
+	sample, ok := latestSample(bucket.Samples)
+	if !ok {
+		logrus.Debugf("存储桶 %v 没有采样点", bucket.Name)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+	}
+
+	usedBytes := float64(sample.UsedKbyte) * kibibyte
+	gauge(bucketUsedBytes, usedBytes)
+	gauge(bucketObjects, float64(sample.ObjectNum))
+	if bucket.QuotaMaxSize > 0 {
+		gauge(bucketQuotaUsedRatio, usedBytes/float64(bucket.QuotaMaxSize))
+	}
+}
+
+// Buckets 是 Xsky 对象存储桶相关信息的 Response Body 中 os_buckets 数组的元素
+type Buckets struct {
+	Create time.Time   `json:"create"`
+	ID     int         `json:"id"`
+	Name   string      `json:"name"`
+	Owner  ResourceRef `json:"owner"`
+	// QuotaMaxObjects 与 QuotaMaxSize 为 0 时表示未设置配额，QuotaMaxSize 的单位为 Byte
+	QuotaMaxObjects int64           `json:"quota_max_objects"`
+	QuotaMaxSize    int64           `json:"quota_max_size"`
+	Samples         []BucketSamples `json:"samples"`
+	Status          string          `json:"status"`
+	StorageClass    string          `json:"storage_class"`
+	Update          time.Time       `json:"update"`
+}
+
+// BucketSamples 是 Buckets 的子集，一个数组
+type BucketSamples struct {
+	Create    time.Time `json:"create"`
+	ObjectNum int64     `json:"object_num"`
+	UsedKbyte int64     `json:"used_kbyte"`
+}
+
+func (s BucketSamples) created() time.Time { return s.Create }
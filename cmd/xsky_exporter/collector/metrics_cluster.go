@@ -2,24 +2,175 @@ package collector
 
 import (
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
 )
 
 var (
 	// check interface
-	_ scraper.CommonScraper = ScrapeCluster{}
+	_ scraper.DescribableScraper = ScrapeCluster{}
+
+	// 集群已知的状态，上游返回其他状态时同样会作为一个 state 发送
+	clusterStates = []string{"active", "warning", "error", "offline"}
 
 	// 设置 Metric 的基本信息，从 xsky 的接口中获取 cluster 相关的数据。
-	// 由于 cluster 中包含大量内容，如果在抓取 Metrics 时，想要获取其中的所有数据
-	// 则可以将 cluster 的json格式的响应体中的 key 当作 metric 的标签值即可
-	cluster = prometheus.NewDesc(
-		prometheus.BuildFQName(Namespace, "", "cluster_info"),
-		"Xsky Cluster Info",
-		[]string{"comments"}, nil,
+	clusterInfo = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "info"),
+		"Information about the cluster, value is always 1. version is the version of the Xsky software running on the cluster.",
+		prometheus.GaugeValue,
+		[]string{"name", "version", "fs_id", "status"},
+	)
+	clusterStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "status"),
+		"Status of the cluster, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		[]string{"status"},
+	)
+	clusterMaintenanceMode = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "maintenance_mode"),
+		"Whether the cluster is in maintenance mode (1 for maintenance, 0 otherwise).",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// cluster 中的 samples 是集群的容量与性能的采样点，每个有用的字段都作为一个单独的 Metric，并换算为基本单位
+	clusterTotalBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "total_bytes"),
+		"Total raw capacity of the cluster in bytes.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "used_bytes"),
+		"Raw capacity used in the cluster in bytes, including replicas and parity.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterActualBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "actual_bytes"),
+		"Capacity actually consumed by stored data in bytes.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterDataBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "data_bytes"),
+		"Logical size of the data written by clients in bytes.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterErrorBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "error_bytes"),
+		"Capacity on failed or unavailable disks in bytes.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	clusterReadIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "read_iops"),
+		"Read operations per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterWriteIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "write_iops"),
+		"Write operations per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterReadBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "read_bytes_per_second"),
+		"Read bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterWriteBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "write_bytes_per_second"),
+		"Write bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterReadLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "read_latency_seconds"),
+		"Average read latency in seconds.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterWriteLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "write_latency_seconds"),
+		"Average write latency in seconds.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	clusterObjectUploadIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "object_upload_iops"),
+		"Object storage upload operations per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterObjectDownloadIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "object_download_iops"),
+		"Object storage download operations per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterObjectUploadBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "object_upload_bytes_per_second"),
+		"Object storage upload bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterObjectDownloadBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "object_download_bytes_per_second"),
+		"Object storage download bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterObjectMergeSpeed = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "object_merge_speed"),
+		"Object storage merge speed as reported by Xsky.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	clusterRecoveryIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "recovery_iops"),
+		"Recovery operations per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterRecoveryBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "recovery_bytes_per_second"),
+		"Recovery bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterRecoveryRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "recovery_ratio"),
+		"Ratio of data that is being recovered, between 0 and 1.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterHealthyRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "healthy_ratio"),
+		"Ratio of data that is healthy, between 0 and 1.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterDegradedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "degraded_ratio"),
+		"Ratio of data that is degraded, between 0 and 1.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	clusterUnavailableRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "cluster", "unavailable_ratio"),
+		"Ratio of data that is unavailable, between 0 and 1.",
+		prometheus.GaugeValue,
+		nil,
 	)
 )
 
@@ -35,7 +186,37 @@ func (ScrapeCluster) Name() string {
 // Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
 // 该方法用于为 ScrapeCluster 结构体实现 Scraper 接口
 func (ScrapeCluster) Help() string {
-	return "Xsky Cluster Info"
+	return "Xsky cluster information, status, capacity and performance samples"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeCluster 结构体实现 DescribableScraper 接口
+func (ScrapeCluster) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterInfo
+	ch <- clusterStatus
+	ch <- clusterMaintenanceMode
+	ch <- clusterTotalBytes
+	ch <- clusterUsedBytes
+	ch <- clusterActualBytes
+	ch <- clusterDataBytes
+	ch <- clusterErrorBytes
+	ch <- clusterReadIOPS
+	ch <- clusterWriteIOPS
+	ch <- clusterReadBytesPerSecond
+	ch <- clusterWriteBytesPerSecond
+	ch <- clusterReadLatencySeconds
+	ch <- clusterWriteLatencySeconds
+	ch <- clusterObjectUploadIOPS
+	ch <- clusterObjectDownloadIOPS
+	ch <- clusterObjectUploadBytesPerSecond
+	ch <- clusterObjectDownloadBytesPerSecond
+	ch <- clusterObjectMergeSpeed
+	ch <- clusterRecoveryIOPS
+	ch <- clusterRecoveryBytesPerSecond
+	ch <- clusterRecoveryRatio
+	ch <- clusterHealthyRatio
+	ch <- clusterDegradedRatio
+	ch <- clusterUnavailableRatio
 }
 
 // Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 集群信息的具体行为。
@@ -58,11 +239,51 @@ func (ScrapeCluster) Scrape(client scraper.CommonClient, ch chan<- prometheus.Me
 		return err
 	}
 
-	// 根据 Response Body 获取用户使用量
-	fmt.Printf("当前用户已经使用了 %v KiB\n", data.Cluster.Samples[0].UsedKbyte)
-	// cluster 中各种数据的 key 可以作为 metric 的标签值，cluster 中数据的值，就是该 metric 的值
-	ch <- prometheus.MustNewConstMetric(cluster, prometheus.GaugeValue, float64(data.Cluster.Samples[0].UsedKbyte), "used_kbyte")
-	ch <- prometheus.MustNewConstMetric(cluster, prometheus.GaugeValue, float64(data.Cluster.Samples[0].ActualKbyte), "actual_kbyte")
+	// 集群的基本信息与状态不依赖采样点，没有采样点时同样发送
+	c := data.Cluster
+	logrus.Debugf("集群 %v 的版本为 %v，状态为 %v", c.Name, c.Version, c.Status)
+	ch <- prometheus.MustNewConstMetric(clusterInfo, prometheus.GaugeValue, 1, c.Name, c.Version, c.FsID, c.Status)
+	stateSet(ch, clusterStatus, clusterStates, c.Status)
+	ch <- prometheus.MustNewConstMetric(clusterMaintenanceMode, prometheus.GaugeValue, boolToFloat64(c.Maintained))
+
+	sample, ok := latestSample(c.Samples)
+	if !ok {
+		logrus.Debugf("集群 %v 没有采样点", c.Name)
+		return nil
+	}
+	logrus.Debugf("集群已用容量 %v KiB，采样时间 %v", sample.UsedKbyte, sample.Create)
+
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value))
+	}
+
+	// 容量
+	gauge(clusterTotalBytes, float64(sample.TotalKbyte)*kibibyte)
+	gauge(clusterUsedBytes, float64(sample.UsedKbyte)*kibibyte)
+	gauge(clusterActualBytes, float64(sample.ActualKbyte)*kibibyte)
+	gauge(clusterDataBytes, float64(sample.DataKbyte)*kibibyte)
+	gauge(clusterErrorBytes, float64(sample.ErrorKbyte)*kibibyte)
+	// 读写性能
+	gauge(clusterReadIOPS, float64(sample.ReadIops))
+	gauge(clusterWriteIOPS, float64(sample.WriteIops))
+	gauge(clusterReadBytesPerSecond, float64(sample.ReadBandwidthKbyte)*kibibyte)
+	gauge(clusterWriteBytesPerSecond, float64(sample.WriteBandwidthKbyte)*kibibyte)
+	gauge(clusterReadLatencySeconds, float64(sample.ReadLatencyUs)/microsecondsPerSecond)
+	gauge(clusterWriteLatencySeconds, float64(sample.WriteLatencyUs)/microsecondsPerSecond)
+	// 对象存储性能
+	gauge(clusterObjectUploadIOPS, float64(sample.OsUpIops))
+	gauge(clusterObjectDownloadIOPS, float64(sample.OsDownIops))
+	gauge(clusterObjectUploadBytesPerSecond, float64(sample.OsUpBandwidthKbyte)*kibibyte)
+	gauge(clusterObjectDownloadBytesPerSecond, float64(sample.OsDownBandwidthKbyte)*kibibyte)
+	gauge(clusterObjectMergeSpeed, float64(sample.OsMergeSpeed))
+	// 数据恢复与健康状态
+	gauge(clusterRecoveryIOPS, float64(sample.RecoveryIops))
+	gauge(clusterRecoveryBytesPerSecond, float64(sample.RecoveryBandwidthKbyte)*kibibyte)
+	gauge(clusterRecoveryRatio, float64(sample.RecoveryPercent)/percentPerRatio)
+	gauge(clusterHealthyRatio, float64(sample.HealthyPercent)/percentPerRatio)
+	gauge(clusterDegradedRatio, float64(sample.DegradedPercent)/percentPerRatio)
+	gauge(clusterUnavailableRatio, float64(sample.UnavailablePercent)/percentPerRatio)
 	return nil
 }
 
@@ -118,3 +339,5 @@ type Samples struct {
 	WriteIops              int       `json:"write_iops"`
 	WriteLatencyUs         int       `json:"write_latency_us"`
 }
+
+func (s Samples) created() time.Time { return s.Create }
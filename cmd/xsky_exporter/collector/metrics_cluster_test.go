@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// staticClient 是返回固定响应的 CommonClient，key 为 endpoint，value 为响应体
+type staticClient map[string]string
+
+func (c staticClient) Request(method string, endpoint string, reqBody io.Reader) ([]byte, error) {
+	body, ok := c[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("unexpected request %s %s", method, endpoint)
+	}
+	return []byte(body), nil
+}
+
+func (c staticClient) Ping() (bool, error) { return true, nil }
+
+func (c staticClient) GetConcurrency() int { return 1 }
+
+// TestScrapeClusterWithoutSamples 检查集群还没有采样点时不是抓取错误，只发送不依赖采样点的 Metric
+func TestScrapeClusterWithoutSamples(t *testing.T) {
+	client := staticClient{"/api/v1/cluster": `{"cluster":{"name":"c1","version":"5.0","fs_id":"1","status":"active","samples":[]}}`}
+	ch := make(chan prometheus.Metric, 100)
+	if err := (ScrapeCluster{}).Scrape(client, ch); err != nil {
+		t.Fatalf("Scrape() = %v", err)
+	}
+	close(ch)
+
+	got := make(map[*prometheus.Desc]int)
+	for m := range ch {
+		got[m.Desc()]++
+	}
+	want := map[*prometheus.Desc]int{clusterInfo: 1, clusterStatus: len(clusterStates), clusterMaintenanceMode: 1}
+	if len(got) != len(want) {
+		t.Errorf("got metrics for %d descs, want %d", len(got), len(want))
+	}
+	for desc, n := range want {
+		if got[desc] != n {
+			t.Errorf("got %d metrics for %v, want %d", got[desc], desc, n)
+		}
+	}
+}
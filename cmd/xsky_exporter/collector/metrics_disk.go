@@ -1,31 +1,169 @@
 package collector
 
 import (
-	"encoding/json"
-	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
 )
 
 var (
 	// check interface
-	_ scraper.CommonScraper = ScrapeDisk{}
+	_ scraper.DescribableScraper = ScrapeDisk{}
 
 	// 设置 Metric 的基本信息，从 xsky 的接口中获取 disk 相关的数据。
-	// 由于 disk 中包含大量内容，如果在抓取 Metrics 时，想要获取其中的所有数据
-	// 则可以将 disk 的json格式的响应体中的 key 当作 metric 的标签值即可
-	diskStatus = prometheus.NewDesc(
-		prometheus.BuildFQName(Namespace, "", "disk_status"),
-		"Xsky Cluster Info",
-		[]string{"disk_id", "host_name"}, nil,
-	)
-	diskCount = prometheus.NewDesc(
+	diskCount = scraper.NewDesc(
 		prometheus.BuildFQName(Namespace, "", "disk_count"),
-		"Xsky Cluster Info",
-		nil, nil,
+		"Total number of disks in the cluster.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// diskLabels 是每块磁盘的 Metric 都具有的标签
+	diskLabels = []string{"disk_id", "host_name", "device"}
+
+	// 磁盘已知的状态，上游返回其他状态时同样会作为一个 state 发送
+	diskStates         = []string{"active", "warning", "error", "offline"}
+	diskActionStates   = []string{"active", "adding", "removing", "rebuilding", "formatting", "error"}
+	diskLightingStates = []string{"on", "off"}
+
+	// 磁盘的状态与正在执行的操作都以 state-set 的形式表示，当前状态的值为 1，其余为 0
+	diskStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "status"),
+		"Status of the disk, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(diskLabels, "status"),
+	)
+	diskActionStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "action_status"),
+		"Action the disk is undergoing, 1 for the current action status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(diskLabels, "action_status"),
+	)
+	diskLightingStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "lighting_status"),
+		"Status of the locate LED of the disk, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(diskLabels, "lighting_status"),
+	)
+	diskUsed = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "used"),
+		"Whether the disk is used by the cluster (1 for used, 0 for unused).",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskIsCache = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "is_cache"),
+		"Whether the disk is a cache disk (1 for cache, 0 for data).",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskIsRoot = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "is_root"),
+		"Whether the disk holds the root file system of the host (1 for root, 0 otherwise).",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskPowerSafe = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "power_safe"),
+		"Whether the disk has power loss protection (1 for protected, 0 otherwise).",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+
+	diskInfo = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "info"),
+		"Information about the disk, value is always 1.",
+		prometheus.GaugeValue,
+		append(diskLabels, "model", "serial", "disk_type", "wwid", "slot_id", "enclosure_id"),
+	)
+
+	// 以下 Metric 来自磁盘最新的采样点
+	diskIOUtilizationRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "io_utilization_ratio"),
+		"Ratio of time the disk was busy serving IO, between 0 and 1.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskAvgQueueLength = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "avg_queue_length"),
+		"Average number of requests queued on the disk.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskReadIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "read_iops"),
+		"Read operations per second.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskWriteIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "write_iops"),
+		"Write operations per second.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskReadBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "read_bytes_per_second"),
+		"Read bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskWriteBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "write_bytes_per_second"),
+		"Write bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskReadWaitSeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "read_wait_seconds"),
+		"Average time a read request waited to be served, in seconds.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskWriteWaitSeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "write_wait_seconds"),
+		"Average time a write request waited to be served, in seconds.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskTotalBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "total_bytes"),
+		"Capacity of the disk available to the cluster in bytes.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "used_bytes"),
+		"Capacity used on the disk in bytes.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskUsedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "used_ratio"),
+		"Ratio of the disk capacity that is used, between 0 and 1.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskOmapTotalBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "omap_total_bytes"),
+		"Capacity of the OMAP (object metadata) area on the disk in bytes.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskOmapUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "omap_used_bytes"),
+		"Capacity used in the OMAP (object metadata) area on the disk in bytes.",
+		prometheus.GaugeValue,
+		diskLabels,
+	)
+	diskOmapUsedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "disk", "omap_used_ratio"),
+		"Ratio of the OMAP (object metadata) area that is used, between 0 and 1.",
+		prometheus.GaugeValue,
+		diskLabels,
 	)
 )
 
@@ -41,50 +179,110 @@ func (ScrapeDisk) Name() string {
 // Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
 // 该方法用于为 ScrapeDisk 结构体实现 Scraper 接口
 func (ScrapeDisk) Help() string {
-	return "Xsky Cluster Info"
+	return "Xsky disk status, information and performance samples"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeDisk 结构体实现 DescribableScraper 接口
+func (ScrapeDisk) Describe(ch chan<- *prometheus.Desc) {
+	ch <- diskCount
+	ch <- diskStatus
+	ch <- diskActionStatus
+	ch <- diskLightingStatus
+	ch <- diskUsed
+	ch <- diskIsCache
+	ch <- diskIsRoot
+	ch <- diskPowerSafe
+	ch <- diskInfo
+	ch <- diskIOUtilizationRatio
+	ch <- diskAvgQueueLength
+	ch <- diskReadIOPS
+	ch <- diskWriteIOPS
+	ch <- diskReadBytesPerSecond
+	ch <- diskWriteBytesPerSecond
+	ch <- diskReadWaitSeconds
+	ch <- diskWriteWaitSeconds
+	ch <- diskTotalBytes
+	ch <- diskUsedBytes
+	ch <- diskUsedRatio
+	ch <- diskOmapTotalBytes
+	ch <- diskOmapUsedBytes
+	ch <- diskOmapUsedRatio
 }
 
 // Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 集群信息的具体行为。
 // 该方法用于为 ScrapeDisk 结构体实现 Scraper 接口
 func (ScrapeDisk) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
-	// 声明需要绑定的 响应体 与 结构体
-	var (
-		respBody []byte
-		data     DisksJSON
-		status   float64
-	)
-
-	// 根据 URI 获取 Response Body，获取 disk 相关的信息。里面包含大量内容
-	url := "/api/v1/disks"
-	if respBody, err = client.Request("GET", url, nil); err != nil {
-		return err
-	}
-
-	// 绑定 Body 与 struct
-	if err = json.Unmarshal(respBody, &data); err != nil {
+	// 磁盘的数量可能超过一页，分页获取所有磁盘，以保证 disk_count 与每块磁盘的 Metric 一致
+	disks, err := listAll[Disks](client, "/api/v1/disks", "disks")
+	if err != nil {
 		return err
 	}
 
-	// 根据 Response Body 获取用户使用量
-	fmt.Printf("当前一共有 %v 块磁盘\n", data.Paging.TotalCount)
+	logrus.Debugf("当前一共有 %v 块磁盘", len(disks))
 	// disk 中各种数据的 key 可以作为 metric 的标签值，disk 中数据的值，就是该 metric 的值
-	ch <- prometheus.MustNewConstMetric(diskCount, prometheus.GaugeValue, float64(data.Paging.TotalCount))
-	for i := 0; i < len(data.Disks); i++ {
-		if data.Disks[i].ActionStatus == "active" {
-			status = 1
-		}
-		ch <- prometheus.MustNewConstMetric(diskStatus, prometheus.GaugeValue, status, strconv.Itoa(data.Disks[i].ID), data.Disks[i].Host.Name)
+	ch <- prometheus.MustNewConstMetric(diskCount, prometheus.GaugeValue, float64(len(disks)))
+	for _, disk := range disks {
+		scrapeDiskStatus(disk, ch)
+		scrapeDiskSamples(disk, ch)
 	}
 	return nil
 }
 
-// DisksJSON is
-type DisksJSON struct {
-	Disks  []Disks `json:"disks"`
-	Paging Paging  `json:"paging"`
+// diskLabelValues 返回磁盘的 diskLabels 标签的值
+func diskLabelValues(disk Disks) []string {
+	return []string{strconv.Itoa(disk.ID), disk.Host.Name, disk.Device}
 }
 
-// Disks is
+// scrapeDiskStatus 发送一块磁盘的状态与各种标志
+func scrapeDiskStatus(disk Disks, ch chan<- prometheus.Metric) {
+	labels := diskLabelValues(disk)
+	stateSet(ch, diskStatus, diskStates, disk.Status, labels...)
+	stateSet(ch, diskActionStatus, diskActionStates, disk.ActionStatus, labels...)
+	stateSet(ch, diskLightingStatus, diskLightingStates, disk.LightingStatus, labels...)
+
+	ch <- prometheus.MustNewConstMetric(diskUsed, prometheus.GaugeValue, boolToFloat64(disk.Used), labels...)
+	ch <- prometheus.MustNewConstMetric(diskIsCache, prometheus.GaugeValue, boolToFloat64(disk.IsCache), labels...)
+	ch <- prometheus.MustNewConstMetric(diskIsRoot, prometheus.GaugeValue, boolToFloat64(disk.IsRoot), labels...)
+	ch <- prometheus.MustNewConstMetric(diskPowerSafe, prometheus.GaugeValue, boolToFloat64(disk.PowerSafe), labels...)
+}
+
+// scrapeDiskSamples 发送一块磁盘的基本信息，以及其最新采样点中的性能与容量数据
+func scrapeDiskSamples(disk Disks, ch chan<- prometheus.Metric) {
+	labels := diskLabelValues(disk)
+	ch <- prometheus.MustNewConstMetric(diskInfo, prometheus.GaugeValue, 1,
+		append(labels, disk.Model, disk.Serial, disk.DiskType, disk.Wwid, disk.SlotID, disk.EnclosureID)...,
+	)
+
+	sample, ok := latestSample(disk.Samples)
+	if !ok {
+		logrus.Debugf("磁盘 %v 没有采样点", disk.ID)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+	}
+
+	// 性能
+	gauge(diskIOUtilizationRatio, sample.IoUtil/percentPerRatio)
+	gauge(diskAvgQueueLength, float64(sample.AvgQueueLen))
+	gauge(diskReadIOPS, float64(sample.ReadIops))
+	gauge(diskWriteIOPS, float64(sample.WriteIops))
+	gauge(diskReadBytesPerSecond, float64(sample.ReadBandwidthKbyte)*kibibyte)
+	gauge(diskWriteBytesPerSecond, float64(sample.WriteBandwidthKbyte)*kibibyte)
+	gauge(diskReadWaitSeconds, float64(sample.ReadWaitUs)/microsecondsPerSecond)
+	gauge(diskWriteWaitSeconds, float64(sample.WriteWaitUs)/microsecondsPerSecond)
+	// 容量
+	gauge(diskTotalBytes, float64(sample.TotalKbyte)*kibibyte)
+	gauge(diskUsedBytes, float64(sample.UsedKbyte)*kibibyte)
+	gauge(diskUsedRatio, sample.UsedPercent/percentPerRatio)
+	gauge(diskOmapTotalBytes, float64(sample.OmapTotalKbyte)*kibibyte)
+	gauge(diskOmapUsedBytes, float64(sample.OmapUsedKbyte)*kibibyte)
+	gauge(diskOmapUsedRatio, sample.OmapUsedPercent/percentPerRatio)
+}
+
+// Disks 是 Xsky Disk 相关信息的 Response Body 中 disks 数组的元素
 type Disks struct {
 	ActionStatus   string        `json:"action_status"`
 	Bytes          int64         `json:"bytes"`
@@ -153,6 +351,8 @@ type DiskSamples struct {
 	WriteWaitUs         int       `json:"write_wait_us"`
 }
 
+func (s DiskSamples) created() time.Time { return s.Create }
+
 // Paging is
 type Paging struct {
 	Count      int `json:"count"`
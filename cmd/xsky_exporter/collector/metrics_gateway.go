@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// gatewayKinds 是所有类型的网关，每种网关都通过自己的接口分页获取网关组，每个网关组中包含多个网关实例
+var gatewayKinds = []struct {
+	kind     string
+	endpoint string
+	key      string
+}{
+	{kind: "s3", endpoint: "/api/v1/os/gateway-groups", key: "os_gateway_groups"},
+	{kind: "nfs", endpoint: "/api/v1/nfs-gateway-groups", key: "nfs_gateway_groups"},
+	{kind: "smb", endpoint: "/api/v1/smb-gateway-groups", key: "smb_gateway_groups"},
+	{kind: "iscsi", endpoint: "/api/v1/iscsi-gateway-groups", key: "iscsi_gateway_groups"},
+}
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapeGateway{}
+
+	// gatewayGroupLabels 是每个网关组的 Metric 都具有的标签，gatewayLabels 是每个网关实例的 Metric 都具有的标签
+	gatewayGroupLabels = []string{"type", "group"}
+	gatewayLabels      = []string{"type", "group", "gateway", "host_name"}
+
+	// 网关组与网关实例已知的状态，上游返回其他状态时同样会作为一个 state 发送
+	gatewayStates = []string{"active", "warning", "error", "offline"}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取 S3/NFS/SMB/iSCSI 网关相关的数据。
+	gatewayGroupInfo = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway_group", "info"),
+		"Information about the gateway group, value is always 1. vip is the virtual IP clients connect to.",
+		prometheus.GaugeValue,
+		append(gatewayGroupLabels, "vip"),
+	)
+	gatewayGroupStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway_group", "status"),
+		"Status of the gateway group, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(gatewayGroupLabels, "status"),
+	)
+	gatewayUp = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway", "up"),
+		"Whether the gateway instance is active and can serve clients (1 for active, 0 otherwise).",
+		prometheus.GaugeValue,
+		gatewayLabels,
+	)
+	gatewayStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway", "status"),
+		"Status of the gateway instance, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(gatewayLabels, "status"),
+	)
+
+	// 以下 Metric 来自网关实例最新的采样点
+	gatewayConnections = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway", "connections"),
+		"Number of client connections to the gateway instance.",
+		prometheus.GaugeValue,
+		gatewayLabels,
+	)
+	gatewayReceiveBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway", "receive_bytes_per_second"),
+		"Bytes received from clients per second by the gateway instance.",
+		prometheus.GaugeValue,
+		gatewayLabels,
+	)
+	gatewayTransmitBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "gateway", "transmit_bytes_per_second"),
+		"Bytes sent to clients per second by the gateway instance.",
+		prometheus.GaugeValue,
+		gatewayLabels,
+	)
+)
+
+// ScrapeGateway 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeGateway struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeGateway 结构体实现 Scraper 接口
+func (ScrapeGateway) Name() string {
+	return "gateway_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeGateway 结构体实现 Scraper 接口
+func (ScrapeGateway) Help() string {
+	return "Xsky S3/NFS/SMB/iSCSI gateway group and instance status, connections and throughput"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeGateway 结构体实现 DescribableScraper 接口
+func (ScrapeGateway) Describe(ch chan<- *prometheus.Desc) {
+	ch <- gatewayGroupInfo
+	ch <- gatewayGroupStatus
+	ch <- gatewayUp
+	ch <- gatewayStatus
+	ch <- gatewayConnections
+	ch <- gatewayReceiveBytesPerSecond
+	ch <- gatewayTransmitBytesPerSecond
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 网关信息的具体行为。
+// 集群没有启用的网关类型视为没有网关，其他原因导致一种网关获取失败时依然会抓取其他类型的网关，最后返回所有错误
+// 该方法用于为 ScrapeGateway 结构体实现 Scraper 接口
+func (ScrapeGateway) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	var errs []error
+	for _, k := range gatewayKinds {
+		groups, err := listAll[GatewayGroups](client, k.endpoint, k.key)
+		if gatewayNotEnabled(err) {
+			logrus.Debugf("集群没有启用 %s 网关: %v", k.kind, err)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s gateways: %w", k.kind, err))
+			continue
+		}
+
+		logrus.Debugf("当前一共有 %v 个 %s 网关组", len(groups), k.kind)
+		for _, group := range groups {
+			scrapeGatewayGroup(k.kind, group, ch)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gatewayNotEnabled 判断 err 是否表示集群没有启用某种网关。
+// 没有部署或者没有授权的网关服务，其接口返回 404 Not Found 或者 501 Not Implemented
+func gatewayNotEnabled(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && (se.StatusCode == http.StatusNotFound || se.StatusCode == http.StatusNotImplemented)
+}
+
+// scrapeGatewayGroup 发送一个网关组及其所有网关实例的状态与采样数据
+func scrapeGatewayGroup(kind string, group GatewayGroups, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(gatewayGroupInfo, prometheus.GaugeValue, 1, kind, group.Name, group.Vip)
+	stateSet(ch, gatewayGroupStatus, gatewayStates, group.Status, kind, group.Name)
+
+	for _, gateway := range group.Gateways {
+		labels := []string{kind, group.Name, gateway.Name, gateway.Host.Name}
+		ch <- prometheus.MustNewConstMetric(gatewayUp, prometheus.GaugeValue, boolToFloat64(gateway.Status == "active"), labels...)
+		stateSet(ch, gatewayStatus, gatewayStates, gateway.Status, labels...)
+
+		sample, ok := latestSample(gateway.Samples)
+		if !ok {
+			logrus.Debugf("网关 %v 没有采样点", gateway.Name)
+			continue
+		}
+		// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+		gauge := func(desc *prometheus.Desc, value float64) {
+			ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+		}
+		gauge(gatewayConnections, float64(sample.ConnectionNum))
+		gauge(gatewayReceiveBytesPerSecond, float64(sample.RxBandwidthKbyte)*kibibyte)
+		gauge(gatewayTransmitBytesPerSecond, float64(sample.TxBandwidthKbyte)*kibibyte)
+	}
+}
+
+// GatewayGroups 是 Xsky 各种网关组相关信息的 Response Body 中网关组数组的元素
+type GatewayGroups struct {
+	Create   time.Time  `json:"create"`
+	Gateways []Gateways `json:"gateways"`
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	Status   string     `json:"status"`
+	Update   time.Time  `json:"update"`
+	Vip      string     `json:"vip"`
+}
+
+// Gateways 是 GatewayGroups 的子集，网关组中的网关实例
+type Gateways struct {
+	ID      int              `json:"id"`
+	IP      string           `json:"ip"`
+	Host    Host             `json:"host"`
+	Name    string           `json:"name"`
+	Port    int              `json:"port"`
+	Samples []GatewaySamples `json:"samples"`
+	Status  string           `json:"status"`
+}
+
+// GatewaySamples 是 Gateways 的子集，一个数组
+type GatewaySamples struct {
+	ConnectionNum    int       `json:"connection_num"`
+	Create           time.Time `json:"create"`
+	RxBandwidthKbyte int64     `json:"rx_bandwidth_kbyte"`
+	TxBandwidthKbyte int64     `json:"tx_bandwidth_kbyte"`
+}
+
+func (s GatewaySamples) created() time.Time { return s.Create }
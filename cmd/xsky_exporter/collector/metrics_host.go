@@ -0,0 +1,198 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapeHost{}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取 host 相关的数据。
+	hostCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "host_count"),
+		"Total number of hosts in the cluster.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// hostLabels 是每个节点的 Metric 都具有的标签
+	hostLabels = []string{"host_id", "host_name"}
+
+	// 节点已知的状态，上游返回其他状态时同样会作为一个 state 发送
+	hostStates = []string{"active", "warning", "error", "offline"}
+
+	hostInfo = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "info"),
+		"Information about the host, value is always 1. roles is a comma separated list of the services running on the host.",
+		prometheus.GaugeValue,
+		append(hostLabels, "admin_ip", "public_ip", "private_ip", "roles"),
+	)
+	hostStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "status"),
+		"Status of the host, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(hostLabels, "status"),
+	)
+	hostMaintenanceMode = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "maintenance_mode"),
+		"Whether the host is in maintenance mode (1 for maintenance, 0 otherwise).",
+		prometheus.GaugeValue,
+		hostLabels,
+	)
+
+	// 以下 Metric 来自节点最新的采样点
+	hostCPUUtilizationRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "cpu_utilization_ratio"),
+		"Ratio of CPU time in use on the host, between 0 and 1.",
+		prometheus.GaugeValue,
+		hostLabels,
+	)
+	hostMemoryTotalBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "memory_total_bytes"),
+		"Total memory of the host in bytes.",
+		prometheus.GaugeValue,
+		hostLabels,
+	)
+	hostMemoryUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "memory_used_bytes"),
+		"Memory in use on the host in bytes.",
+		prometheus.GaugeValue,
+		hostLabels,
+	)
+	hostNetworkReceiveBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "network_receive_bytes_per_second"),
+		"Bytes received per second on the public or private network of the host.",
+		prometheus.GaugeValue,
+		append(hostLabels, "network"),
+	)
+	hostNetworkTransmitBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "host", "network_transmit_bytes_per_second"),
+		"Bytes transmitted per second on the public or private network of the host.",
+		prometheus.GaugeValue,
+		append(hostLabels, "network"),
+	)
+)
+
+// ScrapeHost 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeHost struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeHost 结构体实现 Scraper 接口
+func (ScrapeHost) Name() string {
+	return "host_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeHost 结构体实现 Scraper 接口
+func (ScrapeHost) Help() string {
+	return "Xsky host status, information and resource usage samples"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeHost 结构体实现 DescribableScraper 接口
+func (ScrapeHost) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostCount
+	ch <- hostInfo
+	ch <- hostStatus
+	ch <- hostMaintenanceMode
+	ch <- hostCPUUtilizationRatio
+	ch <- hostMemoryTotalBytes
+	ch <- hostMemoryUsedBytes
+	ch <- hostNetworkReceiveBytesPerSecond
+	ch <- hostNetworkTransmitBytesPerSecond
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 节点信息的具体行为。
+// 该方法用于为 ScrapeHost 结构体实现 Scraper 接口
+func (ScrapeHost) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	// 节点的数量可能超过一页，分页获取所有节点，以保证 host_count 与每个节点的 Metric 一致
+	hosts, err := listAll[Hosts](client, "/api/v1/hosts", "hosts")
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("当前一共有 %v 个节点", len(hosts))
+	ch <- prometheus.MustNewConstMetric(hostCount, prometheus.GaugeValue, float64(len(hosts)))
+	for _, host := range hosts {
+		scrapeHostStatus(host, ch)
+		scrapeHostSamples(host, ch)
+	}
+	return nil
+}
+
+// hostLabelValues 返回节点的 hostLabels 标签的值
+func hostLabelValues(host Hosts) []string {
+	return []string{strconv.Itoa(host.ID), host.Name}
+}
+
+// scrapeHostStatus 发送一个节点的基本信息与状态
+func scrapeHostStatus(host Hosts, ch chan<- prometheus.Metric) {
+	labels := hostLabelValues(host)
+	ch <- prometheus.MustNewConstMetric(hostInfo, prometheus.GaugeValue, 1,
+		append(labels, host.AdminIP, host.PublicIP, host.PrivateIP, host.Roles)...,
+	)
+	stateSet(ch, hostStatus, hostStates, host.Status, labels...)
+	ch <- prometheus.MustNewConstMetric(hostMaintenanceMode, prometheus.GaugeValue, boolToFloat64(host.Maintained), labels...)
+}
+
+// scrapeHostSamples 发送一个节点最新采样点中的 CPU、内存与网络数据
+func scrapeHostSamples(host Hosts, ch chan<- prometheus.Metric) {
+	labels := hostLabelValues(host)
+	sample, ok := latestSample(host.Samples)
+	if !ok {
+		logrus.Debugf("节点 %v 没有采样点", host.Name)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64, labelValues ...string) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels, labelValues...)...))
+	}
+
+	gauge(hostCPUUtilizationRatio, sample.CPUUtil/percentPerRatio)
+	gauge(hostMemoryTotalBytes, float64(sample.MemTotalKbyte)*kibibyte)
+	gauge(hostMemoryUsedBytes, float64(sample.MemUsedKbyte)*kibibyte)
+	gauge(hostNetworkReceiveBytesPerSecond, float64(sample.PublicRxBandwidthKbyte)*kibibyte, "public")
+	gauge(hostNetworkTransmitBytesPerSecond, float64(sample.PublicTxBandwidthKbyte)*kibibyte, "public")
+	gauge(hostNetworkReceiveBytesPerSecond, float64(sample.PrivateRxBandwidthKbyte)*kibibyte, "private")
+	gauge(hostNetworkTransmitBytesPerSecond, float64(sample.PrivateTxBandwidthKbyte)*kibibyte, "private")
+}
+
+// Hosts 是 Xsky Host 相关信息的 Response Body 中 hosts 数组的元素
+type Hosts struct {
+	ActionStatus string        `json:"action_status"`
+	AdminIP      string        `json:"admin_ip"`
+	Create       time.Time     `json:"create"`
+	CPUModel     string        `json:"cpu_model"`
+	DiskNum      int           `json:"disk_num"`
+	HostType     string        `json:"host_type"`
+	ID           int           `json:"id"`
+	Maintained   bool          `json:"maintained"`
+	Model        string        `json:"model"`
+	Name         string        `json:"name"`
+	PrivateIP    string        `json:"private_ip"`
+	PublicIP     string        `json:"public_ip"`
+	Roles        string        `json:"roles"`
+	Samples      []HostSamples `json:"samples"`
+	Status       string        `json:"status"`
+	Update       time.Time     `json:"update"`
+}
+
+// HostSamples 是 Hosts 的子集，一个数组
+type HostSamples struct {
+	CPUUtil                 float64   `json:"cpu_util"`
+	Create                  time.Time `json:"create"`
+	MemTotalKbyte           int64     `json:"mem_total_kbyte"`
+	MemUsedKbyte            int64     `json:"mem_used_kbyte"`
+	PrivateRxBandwidthKbyte int64     `json:"private_rx_bandwidth_kbyte"`
+	PrivateTxBandwidthKbyte int64     `json:"private_tx_bandwidth_kbyte"`
+	PublicRxBandwidthKbyte  int64     `json:"public_rx_bandwidth_kbyte"`
+	PublicTxBandwidthKbyte  int64     `json:"public_tx_bandwidth_kbyte"`
+}
+
+func (s HostSamples) created() time.Time { return s.Create }
@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapeObjectUser{}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取对象存储用户相关的数据。
+	objectUserCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "object_user_count"),
+		"Total number of object storage users in the cluster.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// objectUserLabels 是每个对象存储用户的 Metric 都具有的标签
+	objectUserLabels = []string{"user"}
+
+	objectUserBuckets = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "object_user", "buckets"),
+		"Number of buckets owned by the object storage user.",
+		prometheus.GaugeValue,
+		objectUserLabels,
+	)
+	objectUserUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "object_user", "used_bytes"),
+		"Size of the objects owned by the object storage user in bytes.",
+		prometheus.GaugeValue,
+		objectUserLabels,
+	)
+	objectUserObjects = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "object_user", "objects"),
+		"Number of objects owned by the object storage user.",
+		prometheus.GaugeValue,
+		objectUserLabels,
+	)
+	// 以下 Metric 只有用户设置了配额时才会产生
+	objectUserQuotaBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "object_user", "quota_bytes"),
+		"Maximum size of the objects owned by the object storage user in bytes, only present when a size quota is set.",
+		prometheus.GaugeValue,
+		objectUserLabels,
+	)
+	objectUserQuotaObjects = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "object_user", "quota_objects"),
+		"Maximum number of objects owned by the object storage user, only present when an object quota is set.",
+		prometheus.GaugeValue,
+		objectUserLabels,
+	)
+	objectUserQuotaUsedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "object_user", "quota_used_ratio"),
+		"Ratio of the size quota of the object storage user that is used, only present when a size quota is set.",
+		prometheus.GaugeValue,
+		objectUserLabels,
+	)
+)
+
+// ScrapeObjectUser 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeObjectUser struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeObjectUser 结构体实现 Scraper 接口
+func (ScrapeObjectUser) Name() string {
+	return "object_user_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeObjectUser 结构体实现 Scraper 接口
+func (ScrapeObjectUser) Help() string {
+	return "Xsky object storage user usage and quotas"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeObjectUser 结构体实现 DescribableScraper 接口
+func (ScrapeObjectUser) Describe(ch chan<- *prometheus.Desc) {
+	ch <- objectUserCount
+	ch <- objectUserBuckets
+	ch <- objectUserUsedBytes
+	ch <- objectUserObjects
+	ch <- objectUserQuotaBytes
+	ch <- objectUserQuotaObjects
+	ch <- objectUserQuotaUsedRatio
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 对象存储用户信息的具体行为。
+// 该方法用于为 ScrapeObjectUser 结构体实现 Scraper 接口
+func (ScrapeObjectUser) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	users, err := listAll[ObjectUsers](client, "/api/v1/os/users", "os_users")
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("当前一共有 %v 个对象存储用户", len(users))
+	ch <- prometheus.MustNewConstMetric(objectUserCount, prometheus.GaugeValue, float64(len(users)))
+	for _, user := range users {
+		scrapeObjectUserUsage(user, ch)
+	}
+	return nil
+}
+
+// scrapeObjectUserUsage 发送一个对象存储用户的配额，以及其最新采样点中的使用量
+func scrapeObjectUserUsage(user ObjectUsers, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(objectUserBuckets, prometheus.GaugeValue, float64(user.BucketNum), user.Name)
+	if user.QuotaMaxSize > 0 {
+		ch <- prometheus.MustNewConstMetric(objectUserQuotaBytes, prometheus.GaugeValue, float64(user.QuotaMaxSize), user.Name)
+	}
+	if user.QuotaMaxObjects > 0 {
+		ch <- prometheus.MustNewConstMetric(objectUserQuotaObjects, prometheus.GaugeValue, float64(user.QuotaMaxObjects), user.Name)
+	}
+
+	sample, ok := latestSample(user.Samples)
+	if !ok {
+		logrus.Debugf("对象存储用户 %v 没有采样点", user.Name)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, user.Name))
+	}
+
+	usedBytes := float64(sample.UsedKbyte) * kibibyte
+	gauge(objectUserUsedBytes, usedBytes)
+	gauge(objectUserObjects, float64(sample.ObjectNum))
+	if user.QuotaMaxSize > 0 {
+		gauge(objectUserQuotaUsedRatio, usedBytes/float64(user.QuotaMaxSize))
+	}
+}
+
+// ObjectUsers 是 Xsky 对象存储用户相关信息的 Response Body 中 os_users 数组的元素
+type ObjectUsers struct {
+	BucketNum int       `json:"bucket_num"`
+	Create    time.Time `json:"create"`
+	Email     string    `json:"email"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	// QuotaMaxObjects 与 QuotaMaxSize 为 0 时表示未设置配额，QuotaMaxSize 的单位为 Byte
+	QuotaMaxObjects int64               `json:"quota_max_objects"`
+	QuotaMaxSize    int64               `json:"quota_max_size"`
+	Samples         []ObjectUserSamples `json:"samples"`
+	Status          string              `json:"status"`
+	Update          time.Time           `json:"update"`
+}
+
+// ObjectUserSamples 是 ObjectUsers 的子集，一个数组
+type ObjectUserSamples struct {
+	Create    time.Time `json:"create"`
+	ObjectNum int64     `json:"object_num"`
+	UsedKbyte int64     `json:"used_kbyte"`
+}
+
+func (s ObjectUserSamples) created() time.Time { return s.Create }
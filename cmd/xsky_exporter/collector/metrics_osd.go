@@ -0,0 +1,200 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapeOSD{}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取 OSD 相关的数据。
+	osdCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "osd_count"),
+		"Total number of OSDs in the cluster.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// osdLabels 是每个 OSD 的 Metric 都具有的标签
+	osdLabels = []string{"osd_id", "osd_name", "host_name"}
+
+	osdInfo = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "info"),
+		"Information about the OSD and the disk backing it, value is always 1. disk_id matches xsky_disk_info.",
+		prometheus.GaugeValue,
+		append(osdLabels, "disk_id", "device"),
+	)
+	osdUp = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "up"),
+		"Whether the OSD is up (1 for up, 0 for down).",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+	osdIn = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "in"),
+		"Whether the OSD is in the cluster and holds data (1 for in, 0 for out).",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+	osdWeight = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "weight"),
+		"CRUSH weight of the OSD.",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+
+	// 以下 Metric 来自 OSD 最新的采样点
+	osdTotalBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "total_bytes"),
+		"Capacity of the OSD in bytes.",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+	osdUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "used_bytes"),
+		"Capacity used on the OSD in bytes.",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+	osdUsedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "used_ratio"),
+		"Ratio of the OSD capacity that is used, between 0 and 1.",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+	osdApplyLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "apply_latency_seconds"),
+		"Average time to apply a write to the file system of the OSD, in seconds.",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+	osdCommitLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "osd", "commit_latency_seconds"),
+		"Average time to commit a write to the journal of the OSD, in seconds.",
+		prometheus.GaugeValue,
+		osdLabels,
+	)
+)
+
+// ScrapeOSD 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapeOSD struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapeOSD 结构体实现 Scraper 接口
+func (ScrapeOSD) Name() string {
+	return "osd_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapeOSD 结构体实现 Scraper 接口
+func (ScrapeOSD) Help() string {
+	return "Xsky OSD up/in state, weight, utilisation and latency"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapeOSD 结构体实现 DescribableScraper 接口
+func (ScrapeOSD) Describe(ch chan<- *prometheus.Desc) {
+	ch <- osdCount
+	ch <- osdInfo
+	ch <- osdUp
+	ch <- osdIn
+	ch <- osdWeight
+	ch <- osdTotalBytes
+	ch <- osdUsedBytes
+	ch <- osdUsedRatio
+	ch <- osdApplyLatencySeconds
+	ch <- osdCommitLatencySeconds
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky OSD 信息的具体行为。
+// 该方法用于为 ScrapeOSD 结构体实现 Scraper 接口
+func (ScrapeOSD) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	osds, err := listAll[Osds](client, "/api/v1/osds", "osds")
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("当前一共有 %v 个 OSD", len(osds))
+	ch <- prometheus.MustNewConstMetric(osdCount, prometheus.GaugeValue, float64(len(osds)))
+	for _, osd := range osds {
+		scrapeOSDStatus(osd, ch)
+		scrapeOSDSamples(osd, ch)
+	}
+	return nil
+}
+
+// osdLabelValues 返回 OSD 的 osdLabels 标签的值
+func osdLabelValues(osd Osds) []string {
+	return []string{strconv.Itoa(osd.ID), osd.Name, osd.Host.Name}
+}
+
+// scrapeOSDStatus 发送一个 OSD 的基本信息、up/in 状态与权重
+func scrapeOSDStatus(osd Osds, ch chan<- prometheus.Metric) {
+	labels := osdLabelValues(osd)
+	ch <- prometheus.MustNewConstMetric(osdInfo, prometheus.GaugeValue, 1,
+		append(labels, strconv.Itoa(osd.Disk.ID), osd.Disk.Device)...,
+	)
+	ch <- prometheus.MustNewConstMetric(osdUp, prometheus.GaugeValue, boolToFloat64(osd.Up), labels...)
+	ch <- prometheus.MustNewConstMetric(osdIn, prometheus.GaugeValue, boolToFloat64(osd.In), labels...)
+	ch <- prometheus.MustNewConstMetric(osdWeight, prometheus.GaugeValue, osd.Weight, labels...)
+}
+
+// scrapeOSDSamples 发送一个 OSD 最新采样点中的容量与延迟
+func scrapeOSDSamples(osd Osds, ch chan<- prometheus.Metric) {
+	labels := osdLabelValues(osd)
+	sample, ok := latestSample(osd.Samples)
+	if !ok {
+		logrus.Debugf("OSD %v 没有采样点", osd.Name)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+	}
+
+	gauge(osdTotalBytes, float64(sample.TotalKbyte)*kibibyte)
+	gauge(osdUsedBytes, float64(sample.UsedKbyte)*kibibyte)
+	gauge(osdUsedRatio, sample.UsedPercent/percentPerRatio)
+	gauge(osdApplyLatencySeconds, float64(sample.ApplyLatencyUs)/microsecondsPerSecond)
+	gauge(osdCommitLatencySeconds, float64(sample.CommitLatencyUs)/microsecondsPerSecond)
+}
+
+// Osds 是 Xsky OSD 相关信息的 Response Body 中 osds 数组的元素
+type Osds struct {
+	ActionStatus string       `json:"action_status"`
+	Create       time.Time    `json:"create"`
+	Disk         OsdDisk      `json:"disk"`
+	Host         Host         `json:"host"`
+	ID           int          `json:"id"`
+	In           bool         `json:"in"`
+	Name         string       `json:"name"`
+	Samples      []OsdSamples `json:"samples"`
+	Status       string       `json:"status"`
+	Up           bool         `json:"up"`
+	Update       time.Time    `json:"update"`
+	Weight       float64      `json:"weight"`
+}
+
+// OsdDisk 是 Osds 的子集，OSD 所在的磁盘
+type OsdDisk struct {
+	Device string `json:"device"`
+	ID     int    `json:"id"`
+}
+
+// OsdSamples 是 Osds 的子集，一个数组
+type OsdSamples struct {
+	ApplyLatencyUs  int       `json:"apply_latency_us"`
+	CommitLatencyUs int       `json:"commit_latency_us"`
+	Create          time.Time `json:"create"`
+	TotalKbyte      int64     `json:"total_kbyte"`
+	UsedKbyte       int64     `json:"used_kbyte"`
+	UsedPercent     float64   `json:"used_percent"`
+}
+
+func (s OsdSamples) created() time.Time { return s.Create }
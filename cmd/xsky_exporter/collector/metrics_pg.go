@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapePG{}
+
+	// PG 已知的状态，每个状态都会产生一个 xsky_pg_state，没有处于该状态的 PG 时值为 0
+	pgStates = []string{"active", "clean", "degraded", "recovering", "recovery_wait", "backfilling", "backfill_wait", "undersized", "peering", "remapped", "stale", "inconsistent", "down", "incomplete"}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取 PG(placement group) 相关的数据。
+	pgTotal = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pg", "total"),
+		"Total number of placement groups in the cluster.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	pgActiveClean = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pg", "active_clean"),
+		"Number of placement groups that are exactly active+clean, i.e. healthy.",
+		prometheus.GaugeValue,
+		nil,
+	)
+	pgState = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pg", "state"),
+		"Number of placement groups whose state includes the given state, a placement group in active+recovering+degraded is counted in active, recovering and degraded.",
+		prometheus.GaugeValue,
+		[]string{"state"},
+	)
+)
+
+// ScrapePG 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapePG struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapePG 结构体实现 Scraper 接口
+func (ScrapePG) Name() string {
+	return "pg_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapePG 结构体实现 Scraper 接口
+func (ScrapePG) Help() string {
+	return "Xsky placement group state counts"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapePG 结构体实现 DescribableScraper 接口
+func (ScrapePG) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pgTotal
+	ch <- pgActiveClean
+	ch <- pgState
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky PG 状态的具体行为。
+// 该方法用于为 ScrapePG 结构体实现 Scraper 接口
+func (ScrapePG) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	// 声明需要绑定的 响应体 与 结构体
+	var (
+		respBody []byte
+		data     pgSummaryJSON
+	)
+
+	// 根据 URI 获取 Response Body，获取各个状态的 PG 的数量
+	url := "/api/v1/cluster/pgs"
+	if respBody, err = client.Request("GET", url, nil); err != nil {
+		return err
+	}
+
+	// 绑定 Body 与 struct
+	if err = json.Unmarshal(respBody, &data); err != nil {
+		return err
+	}
+
+	// 上游返回的是组合状态，e.g. active+recovering+degraded，按其中的每个状态分别累加
+	counts := make(map[string]int, len(pgStates))
+	for _, state := range pgStates {
+		counts[state] = 0
+	}
+	activeClean := 0
+	for _, s := range data.PgSummary.States {
+		if s.Name == "active+clean" {
+			activeClean += s.Num
+		}
+		for _, state := range strings.Split(s.Name, "+") {
+			counts[state] += s.Num
+		}
+	}
+
+	logrus.Debugf("当前一共有 %v 个 PG，其中 %v 个为 active+clean", data.PgSummary.Total, activeClean)
+	ch <- prometheus.MustNewConstMetric(pgTotal, prometheus.GaugeValue, float64(data.PgSummary.Total))
+	ch <- prometheus.MustNewConstMetric(pgActiveClean, prometheus.GaugeValue, float64(activeClean))
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(pgState, prometheus.GaugeValue, float64(count), state)
+	}
+	return nil
+}
+
+// pgSummaryJSON 存储 Xsky PG 状态的 Response Body 的数据
+type pgSummaryJSON struct {
+	PgSummary PgSummary `json:"pg_summary"`
+}
+
+// PgSummary 是 pgSummaryJSON 的子集
+type PgSummary struct {
+	States []PgStates `json:"states"`
+	Total  int        `json:"total"`
+}
+
+// PgStates 是 PgSummary 的子集，处于某个组合状态的 PG 的数量
+type PgStates struct {
+	Name string `json:"name"`
+	Num  int    `json:"num"`
+}
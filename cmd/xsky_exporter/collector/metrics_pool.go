@@ -0,0 +1,260 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// check interface
+	_ scraper.DescribableScraper = ScrapePool{}
+
+	// 设置 Metric 的基本信息，从 xsky 的接口中获取 pool 相关的数据。
+	poolCount = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "pool_count"),
+		"Total number of storage pools in the cluster.",
+		prometheus.GaugeValue,
+		nil,
+	)
+
+	// poolLabels 是每个存储池的 Metric 都具有的标签
+	poolLabels = []string{"pool_id", "pool_name"}
+
+	// 存储池已知的状态，上游返回其他状态时同样会作为一个 state 发送
+	poolStates = []string{"active", "warning", "error", "offline"}
+
+	poolInfo = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "info"),
+		"Information about the storage pool and its data protection policy, value is always 1. replicate_size is set for replicated pools, data_chunk_num and coding_chunk_num for erasure coded pools.",
+		prometheus.GaugeValue,
+		append(poolLabels, "pool_type", "pool_role", "replicate_size", "data_chunk_num", "coding_chunk_num", "failure_domain_type"),
+	)
+	poolStatus = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "status"),
+		"Status of the storage pool, 1 for the current status and 0 for the others.",
+		prometheus.GaugeValue,
+		append(poolLabels, "status"),
+	)
+
+	// 以下 Metric 来自存储池最新的采样点
+	poolTotalBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "total_bytes"),
+		"Total capacity of the storage pool in bytes.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolUsedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "used_bytes"),
+		"Capacity used in the storage pool in bytes.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolProvisionedBytes = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "provisioned_bytes"),
+		"Capacity provisioned to volumes and buckets in the storage pool in bytes, may exceed the total capacity with thin provisioning.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolReadIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "read_iops"),
+		"Read operations per second.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolWriteIOPS = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "write_iops"),
+		"Write operations per second.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolReadBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "read_bytes_per_second"),
+		"Read bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolWriteBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "write_bytes_per_second"),
+		"Write bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolReadLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "read_latency_seconds"),
+		"Average read latency in seconds.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolWriteLatencySeconds = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "write_latency_seconds"),
+		"Average write latency in seconds.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolRecoveryRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "recovery_ratio"),
+		"Ratio of data in the storage pool that is being recovered, between 0 and 1.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolRecoveryBytesPerSecond = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "recovery_bytes_per_second"),
+		"Recovery bandwidth in bytes per second.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+	poolDegradedRatio = scraper.NewDesc(
+		prometheus.BuildFQName(Namespace, "pool", "degraded_ratio"),
+		"Ratio of data in the storage pool that is degraded, between 0 and 1.",
+		prometheus.GaugeValue,
+		poolLabels,
+	)
+)
+
+// ScrapePool 是将要实现 Scraper 接口的一个 Metric 结构体
+type ScrapePool struct{}
+
+// Name 指定自己定义的 抓取器 的名字，与 Metric 的名字不是一个概念，但是一般保持一致
+// 该方法用于为 ScrapePool 结构体实现 Scraper 接口
+func (ScrapePool) Name() string {
+	return "pool_info"
+}
+
+// Help 指定自己定义的 抓取器 的帮助信息，这里的 Help 的内容将会作为命令行标志的帮助信息。与 Metric 的 Help 不是一个概念。
+// 该方法用于为 ScrapePool 结构体实现 Scraper 接口
+func (ScrapePool) Help() string {
+	return "Xsky storage pool capacity, policy and performance samples"
+}
+
+// Describe 将该抓取器会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+// 该方法用于为 ScrapePool 结构体实现 DescribableScraper 接口
+func (ScrapePool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolCount
+	ch <- poolInfo
+	ch <- poolStatus
+	ch <- poolTotalBytes
+	ch <- poolUsedBytes
+	ch <- poolProvisionedBytes
+	ch <- poolReadIOPS
+	ch <- poolWriteIOPS
+	ch <- poolReadBytesPerSecond
+	ch <- poolWriteBytesPerSecond
+	ch <- poolReadLatencySeconds
+	ch <- poolWriteLatencySeconds
+	ch <- poolRecoveryRatio
+	ch <- poolRecoveryBytesPerSecond
+	ch <- poolDegradedRatio
+}
+
+// Scrape 从客户端采集数据，并将其作为 Metric 通过 channel(通道) 发送。主要就是采集 Xsky 存储池信息的具体行为。
+// 该方法用于为 ScrapePool 结构体实现 Scraper 接口
+func (ScrapePool) Scrape(client scraper.CommonClient, ch chan<- prometheus.Metric) (err error) {
+	// 存储池的数量可能超过一页，分页获取所有存储池
+	pools, err := listAll[Pools](client, "/api/v1/pools", "pools")
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("当前一共有 %v 个存储池", len(pools))
+	ch <- prometheus.MustNewConstMetric(poolCount, prometheus.GaugeValue, float64(len(pools)))
+	for _, pool := range pools {
+		scrapePoolStatus(pool, ch)
+		scrapePoolSamples(pool, ch)
+	}
+	return nil
+}
+
+// poolLabelValues 返回存储池的 poolLabels 标签的值
+func poolLabelValues(pool Pools) []string {
+	return []string{strconv.Itoa(pool.ID), pool.Name}
+}
+
+// scrapePoolStatus 发送一个存储池的基本信息与状态
+func scrapePoolStatus(pool Pools, ch chan<- prometheus.Metric) {
+	labels := poolLabelValues(pool)
+	// 副本池只有副本数，纠删码池只有数据块与校验块的数量，不适用的标签为空
+	var replicateSize, dataChunkNum, codingChunkNum string
+	if pool.PoolType == "replicated" {
+		replicateSize = strconv.Itoa(pool.ReplicateSize)
+	} else {
+		dataChunkNum = strconv.Itoa(pool.DataChunkNum)
+		codingChunkNum = strconv.Itoa(pool.CodingChunkNum)
+	}
+	ch <- prometheus.MustNewConstMetric(poolInfo, prometheus.GaugeValue, 1,
+		append(labels, pool.PoolType, pool.PoolRole, replicateSize, dataChunkNum, codingChunkNum, pool.FailureDomainType)...,
+	)
+	stateSet(ch, poolStatus, poolStates, pool.Status, labels...)
+}
+
+// scrapePoolSamples 发送一个存储池最新采样点中的容量、性能与恢复进度
+func scrapePoolSamples(pool Pools, ch chan<- prometheus.Metric) {
+	labels := poolLabelValues(pool)
+	sample, ok := latestSample(pool.Samples)
+	if !ok {
+		logrus.Debugf("存储池 %v 没有采样点", pool.Name)
+		return
+	}
+	// 使用采样点的创建时间作为 Metric 的时间戳，是否保留该时间戳由配置文件中该 Scraper 的 honor_timestamps 决定
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.NewMetricWithTimestamp(sample.Create, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+	}
+
+	// 容量
+	gauge(poolTotalBytes, float64(sample.TotalKbyte)*kibibyte)
+	gauge(poolUsedBytes, float64(sample.UsedKbyte)*kibibyte)
+	gauge(poolProvisionedBytes, float64(sample.ProvisionedKbyte)*kibibyte)
+	// 读写性能
+	gauge(poolReadIOPS, float64(sample.ReadIops))
+	gauge(poolWriteIOPS, float64(sample.WriteIops))
+	gauge(poolReadBytesPerSecond, float64(sample.ReadBandwidthKbyte)*kibibyte)
+	gauge(poolWriteBytesPerSecond, float64(sample.WriteBandwidthKbyte)*kibibyte)
+	gauge(poolReadLatencySeconds, float64(sample.ReadLatencyUs)/microsecondsPerSecond)
+	gauge(poolWriteLatencySeconds, float64(sample.WriteLatencyUs)/microsecondsPerSecond)
+	// 数据恢复
+	gauge(poolRecoveryRatio, float64(sample.RecoveryPercent)/percentPerRatio)
+	gauge(poolRecoveryBytesPerSecond, float64(sample.RecoveryBandwidthKbyte)*kibibyte)
+	gauge(poolDegradedRatio, float64(sample.DegradedPercent)/percentPerRatio)
+}
+
+// Pools 是 Xsky Pool 相关信息的 Response Body 中 pools 数组的元素
+type Pools struct {
+	ActionStatus      string        `json:"action_status"`
+	CodingChunkNum    int           `json:"coding_chunk_num"`
+	Create            time.Time     `json:"create"`
+	DataChunkNum      int           `json:"data_chunk_num"`
+	FailureDomainType string        `json:"failure_domain_type"`
+	ID                int           `json:"id"`
+	Name              string        `json:"name"`
+	PoolRole          string        `json:"pool_role"`
+	PoolType          string        `json:"pool_type"`
+	ReplicateSize     int           `json:"replicate_size"`
+	Samples           []PoolSamples `json:"samples"`
+	Size              int           `json:"size"`
+	Status            string        `json:"status"`
+	Update            time.Time     `json:"update"`
+}
+
+// PoolSamples 是 Pools 的子集，一个数组
+type PoolSamples struct {
+	Create                 time.Time `json:"create"`
+	DegradedPercent        int       `json:"degraded_percent"`
+	HealthyPercent         int       `json:"healthy_percent"`
+	ProvisionedKbyte       int64     `json:"provisioned_kbyte"`
+	ReadBandwidthKbyte     int64     `json:"read_bandwidth_kbyte"`
+	ReadIops               int       `json:"read_iops"`
+	ReadLatencyUs          int       `json:"read_latency_us"`
+	RecoveryBandwidthKbyte int64     `json:"recovery_bandwidth_kbyte"`
+	RecoveryIops           int       `json:"recovery_iops"`
+	RecoveryPercent        int       `json:"recovery_percent"`
+	TotalKbyte             int64     `json:"total_kbyte"`
+	UsedKbyte              int64     `json:"used_kbyte"`
+	WriteBandwidthKbyte    int64     `json:"write_bandwidth_kbyte"`
+	WriteIops              int       `json:"write_iops"`
+	WriteLatencyUs         int       `json:"write_latency_us"`
+}
+
+func (s PoolSamples) created() time.Time { return s.Create }
@@ -2,6 +2,7 @@ package collector
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -10,12 +11,16 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
 	"github.com/bitly/go-simplejson"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // 这三个常量用于给每个 Metrics 名字添加前缀
@@ -44,12 +49,16 @@ func GetToken(opts *XskyOpts) (token string, err error) {
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
 	// 发送 Request 并获取 Response
-	resp, err := (&http.Client{Transport: ts}).Do(req)
-	if err != nil || resp.StatusCode != http.StatusCreated {
+	// Token 在抓取时才获取，需要与其他请求一样受超时时间限制，避免目标无法连接时抓取一直阻塞
+	resp, err := (&http.Client{Transport: ts, Timeout: opts.Timeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GetToken Error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
 		respBody, _ := ioutil.ReadAll(resp.Body)
 		return "", fmt.Errorf("GetToken Error: %v\nResonse:%v", resp.StatusCode, string(respBody))
 	}
-	defer resp.Body.Close()
 
 	// 处理 Response Body,并获取 Token
 	respBody, err := ioutil.ReadAll(resp.Body)
@@ -73,28 +82,34 @@ func GetToken(opts *XskyOpts) (token string, err error) {
 // XskyClient 连接 Xsky 所需信息。实现了 CommonClient 接口
 type XskyClient struct {
 	Client *http.Client
-	Token  string
 	Opts   *XskyOpts
+
+	// Scrape、健康检查的后台 Ping 以及状态页会并发读写 Token，需要通过 currentToken 与 refreshToken 访问
+	tokenMu sync.RWMutex
+	token   string
+	// tokenUpdatedAt 最近一次成功获取 Token 的时间
+	tokenUpdatedAt time.Time
 }
 
-// NewXsykClient 实例化 Xsky 客户端
-func NewXsykClient(opts *XskyOpts) *XskyClient {
+// NewXsykClient 实例化 Xsky 客户端。
+// 实例化时不会获取 Token，第一次抓取时由 Ping 获取，这样多目标模式中单个目标无法连接时只会体现在该目标的 up 上
+func NewXsykClient(opts *XskyOpts) (*XskyClient, error) {
 	uri := opts.URL
 	if !strings.Contains(uri, "://") {
 		uri = "http://" + uri
 	}
 	u, err := url.Parse(uri)
 	if err != nil {
-		panic(fmt.Sprintf("invalid Xsky URL: %s", err))
+		return nil, fmt.Errorf("invalid Xsky URL: %w", err)
 	}
 	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
-		panic(fmt.Sprintf("invalid Xsky URL: %s", uri))
+		return nil, fmt.Errorf("invalid Xsky URL: %s", uri)
 	}
 
 	// ######## 配置 http.Client 的信息 ########
 	rootCAs, err := x509.SystemCertPool()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	// 初始化 TLS 相关配置信息
 	tlsClientConfig := &tls.Config{
@@ -111,37 +126,39 @@ func NewXsykClient(opts *XskyOpts) *XskyClient {
 	}
 	// ######## 配置 http.Client 的信息结束 ########
 
-	//
-	token, err := GetToken(opts)
-	if err != nil {
-		panic(err)
-	}
-
 	return &XskyClient{
-		Opts:  opts,
-		Token: token,
+		Opts: opts,
 		Client: &http.Client{
 			Timeout:   opts.Timeout,
 			Transport: transport,
 		},
-	}
+	}, nil
 }
 
 // Request 建立与 Xsky 的连接，并返回 Response Body
 func (c *XskyClient) Request(method string, endpoint string, reqBody io.Reader) (body []byte, err error) {
+	return c.RequestWithContext(context.Background(), method, endpoint, reqBody)
+}
+
+// RequestWithContext 与 Request 相同，每次请求都会作为 ctx 中 Trace 的一个子 Span。实现了 ContextClient 接口
+func (c *XskyClient) RequestWithContext(ctx context.Context, method string, endpoint string, reqBody io.Reader) (body []byte, err error) {
+	ctx, span := scraper.Tracer().Start(ctx, method+" "+endpoint, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { scraper.EndSpan(span, err) }()
+
 	// 根据认证信息及 endpoint 参数，创建与 Xsky 的连接，并返回 Body 给每个 Metric 采集器
 	url := c.Opts.URL + endpoint
-	logrus.Debugf("request url %s", url)
+	scraper.Logger(ctx).Debugf("request url %s", url)
+	span.SetAttributes(attribute.String("http.method", method), attribute.String("http.url", url))
 
 	// 创建一个新的 Request
 	// req, err := http.NewRequest("GET", url, nil)
-	req, err := http.NewRequest(method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
 	req.SetBasicAuth(c.Opts.Username, c.Opts.password)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Xms-Auth-Token", c.Token)
+	req.Header.Set("Xms-Auth-Token", c.currentToken())
 
 	// 根据新建立的 Request，发起请求，并获取 Response
 	resp, err := c.Client.Do(req)
@@ -149,9 +166,10 @@ func (c *XskyClient) Request(method string, endpoint string, reqBody io.Reader)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error handling request for %s http-statuscode: %s", endpoint, resp.Status)
+		return nil, &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	// 处理 Response Body
@@ -163,20 +181,31 @@ func (c *XskyClient) Request(method string, endpoint string, reqBody io.Reader)
 	return body, nil
 }
 
+// StatusError 是 Xsky 返回非 200 响应时 Request 返回的错误，Scraper 可以根据状态码决定如何处理
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error handling request for %s http-statuscode: %s", e.Endpoint, e.Status)
+}
+
 // Ping 在 Scraper 接口的实现方法 scrape() 中调用。
 // 让 Exporter 每次获取数据时，都检验一下目标设备通信是否正常
 func (c *XskyClient) Ping() (b bool, err error) {
 	logrus.Debugf("每次从 Xsky 并发抓取指标之前，先检查一下目标状态")
 	// 判断是否有 Token
-	if c.Token == "" {
+	token := c.currentToken()
+	if token == "" {
 		logrus.Debugf("Token 为空，开始尝试获取 Token")
-		c.Token, err = GetToken(c.Opts)
-		if err == nil {
-			return true, nil
+		if err = c.refreshToken(); err != nil {
+			return false, err
 		}
-		return false, err
+		return true, nil
 	}
-	logrus.Debugf("Xsky Token 为: %s", c.Token)
+	logrus.Debugf("Xsky Token 为: %s", token)
 
 	// TODO 还需要添加一个认证，当 Token 失效时，也需要重新获取 Token，可以直接
 	logrus.Debugf("Ping Request url %s", c.Opts.URL+"/health")
@@ -207,6 +236,37 @@ func (c *XskyClient) GetConcurrency() int {
 	return c.Opts.Concurrency
 }
 
+// TokenStatus 返回 Token 的状态，不包含 Token 本身。实现了 TokenReporter 接口
+func (c *XskyClient) TokenStatus() scraper.TokenStatus {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	if c.token == "" {
+		return scraper.TokenStatus{}
+	}
+	updatedAt := c.tokenUpdatedAt
+	return scraper.TokenStatus{Present: true, UpdatedAt: &updatedAt}
+}
+
+// currentToken 返回当前使用的 Token，可以并发调用
+func (c *XskyClient) currentToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+
+// refreshToken 重新获取 Token，获取成功后替换当前使用的 Token，可以并发调用
+func (c *XskyClient) refreshToken() error {
+	token, err := GetToken(c.Opts)
+	if err != nil {
+		return err
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = token
+	c.tokenUpdatedAt = time.Now()
+	return nil
+}
+
 // XskyOpts 登录 Xsky 所需属性
 type XskyOpts struct {
 	URL         string
@@ -227,3 +287,16 @@ func (o *XskyOpts) AddFlag() {
 	pflag.DurationVar(&o.Timeout, "time-out", time.Millisecond*1600, "Timeout on HTTP requests to the Xsky API.")
 	pflag.BoolVar(&o.Insecure, "insecure", true, "Disable TLS host verification.")
 }
+
+// ForTarget 返回多目标模式中连接指定目标所需的选项，目标中未设置的认证信息使用 o 中的值
+func (o *XskyOpts) ForTarget(t *scraper.TargetConfig) *XskyOpts {
+	targetOpts := *o
+	targetOpts.URL = t.URL
+	if t.Username != "" {
+		targetOpts.Username = t.Username
+	}
+	if t.Password != "" {
+		targetOpts.password = t.Password
+	}
+	return &targetOpts
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// newFakeXsky 返回一个模拟 Xsky API 的服务端，每次登录都会返回一个新的 Token
+func newFakeXsky(t *testing.T) *httptest.Server {
+	t.Helper()
+	var logins atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/tokens:login", func(w http.ResponseWriter, r *http.Request) {
+		n := logins.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":{"uuid":"token-%d"}}`, n)
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/api/v1/cluster", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Xms-Auth-Token") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestXskyClientTokenConcurrency 在 go test -race 下检查 Scrape、后台 Ping 与状态页并发访问 Token 时没有数据竞争
+func TestXskyClientTokenConcurrency(t *testing.T) {
+	srv := newFakeXsky(t)
+	c := &XskyClient{Client: srv.Client(), Opts: &XskyOpts{URL: srv.URL}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := c.refreshToken(); err != nil {
+				t.Error(err)
+			}
+			if _, err := c.Ping(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// 第一次获取 Token 之前请求可能返回 401，这里只关心并发访问
+			_, _ = c.Request("GET", "/api/v1/cluster", nil)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = c.TokenStatus()
+		}()
+	}
+	wg.Wait()
+
+	status := c.TokenStatus()
+	if !status.Present || status.UpdatedAt == nil {
+		t.Fatalf("TokenStatus() = %+v, want a present token with update time", status)
+	}
+	if _, err := c.Request("GET", "/api/v1/cluster", nil); err != nil {
+		t.Fatalf("Request() after refresh: %v", err)
+	}
+}
+
+// TestNewXsykClientLazyToken 检查实例化客户端时不会连接目标，Token 在第一次 Ping 时获取
+func TestNewXsykClientLazyToken(t *testing.T) {
+	if _, err := NewXsykClient(&XskyOpts{URL: "http://"}); err == nil {
+		t.Error("NewXsykClient() with an empty host should fail")
+	}
+
+	// 无法连接的目标也可以实例化，Ping 返回错误而不是 panic
+	c, err := NewXsykClient(&XskyOpts{URL: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatalf("NewXsykClient() with an unreachable target: %v", err)
+	}
+	if c.TokenStatus().Present {
+		t.Error("token should not be fetched when the client is created")
+	}
+	if ok, err := c.Ping(); ok || err == nil {
+		t.Errorf("Ping() to an unreachable target = %v, %v, want false and an error", ok, err)
+	}
+
+	srv := newFakeXsky(t)
+	c, err = NewXsykClient(&XskyOpts{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.Ping(); !ok || err != nil {
+		t.Fatalf("Ping() = %v, %v, want true", ok, err)
+	}
+	if !c.TokenStatus().Present {
+		t.Error("Ping() should fetch the token")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/DesistDaydream/prometheus-instrumenting/cmd/xsky_exporter/collector"
+	"github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestMetricsCatalog 检查提交的 METRICS.md 与所有 Scraper 声明的 Metric 一致，不一致时需要执行 go generate 重新生成
+func TestMetricsCatalog(t *testing.T) {
+	scraper.Namespace = collector.Namespace
+	allScrapers := []scraper.CommonScraper{}
+	for scraper := range scrapers {
+		allScrapers = append(allScrapers, scraper)
+	}
+	var got bytes.Buffer
+	if err := scraper.WriteCatalog(&got, "markdown", scraper.NewCatalog(allScrapers)); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile("METRICS.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("METRICS.md is out of date, run go generate ./cmd/xsky_exporter/ to update it.\ngot:\n%s", got.String())
+	}
+}
+
+// TestDescribeNoDuplicates 检查每个 Scraper 的 Describe 不会重复发送同一个 Desc。
+// 注册器可以容忍同一个 Collector 中重复的 Desc，所以这类复制粘贴的错误不会在运行时暴露
+func TestDescribeNoDuplicates(t *testing.T) {
+	for s := range scrapers {
+		ds, ok := s.(scraper.DescribableScraper)
+		if !ok {
+			continue
+		}
+		ch := make(chan *prometheus.Desc)
+		go func() {
+			ds.Describe(ch)
+			close(ch)
+		}()
+		seen := make(map[string]bool)
+		for desc := range ch {
+			if seen[desc.String()] {
+				t.Errorf("scraper %s describes %v more than once", s.Name(), desc)
+			}
+			seen[desc.String()] = true
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// CatalogEntry 是指标目录中的一条记录，描述了一个 Metric 的基本信息以及产生该 Metric 的 Scraper
+type CatalogEntry struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Help    string   `json:"help"`
+	Labels  []string `json:"labels"`
+	Scraper string   `json:"scraper"`
+}
+
+// NewCatalog 根据 Scraper 声明的 Desc 生成指标目录，本程序默认自带的 Metrics 也包含在其中。
+// 未实现 DescribableScraper 接口的 Scraper 无法获取其 Metric 信息，将会被忽略。
+func NewCatalog(css []CommonScraper) []CatalogEntry {
+	entries := catalogEntries(Subsystem, NewMetrics(nil).Describe)
+
+	for _, cs := range css {
+		ds, ok := cs.(DescribableScraper)
+		if !ok {
+			logrus.WithField("scraper", cs.Name()).Warn("Scraper 未实现 Describe 方法，无法生成指标目录")
+			continue
+		}
+		entries = append(entries, catalogEntries(cs.Name(), ds.Describe)...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Scraper != entries[j].Scraper {
+			return entries[i].Scraper < entries[j].Scraper
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// catalogEntries 执行 describe 并将获取到的 Desc 转换为指标目录中的记录
+func catalogEntries(scraperName string, describe func(ch chan<- *prometheus.Desc)) []CatalogEntry {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		describe(ch)
+		close(ch)
+	}()
+
+	var entries []CatalogEntry
+	for desc := range ch {
+		md, ok := LookupDesc(desc)
+		if !ok {
+			logrus.WithField("scraper", scraperName).Warnf("Desc 不是通过 scraper.NewDesc 创建的: %v", desc)
+			md = MetricDesc{Name: desc.String()}
+		}
+		labels := md.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		entries = append(entries, CatalogEntry{
+			Name:    md.Name,
+			Type:    md.TypeString(),
+			Help:    md.Help,
+			Labels:  labels,
+			Scraper: scraperName,
+		})
+	}
+	return entries
+}
+
+// WriteCatalog 将指标目录以指定的格式写入 w 中，format 可以是 markdown 或 json
+func WriteCatalog(w io.Writer, format string, entries []CatalogEntry) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "markdown", "md":
+		if _, err := fmt.Fprintln(w, "| Name | Type | Help | Labels | Scraper |"); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, "| --- | --- | --- | --- | --- |"); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if _, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n",
+				e.Name, e.Type, strings.ReplaceAll(e.Help, "|", "\\|"), strings.Join(e.Labels, ", "), e.Scraper,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown catalog format %q, must be markdown or json", format)
+	}
+}
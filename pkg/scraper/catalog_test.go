@@ -0,0 +1,106 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// describeScraper 是实现了 DescribableScraper 接口的测试 Scraper
+type describeScraper struct {
+	fakeScraper
+	descs []*prometheus.Desc
+}
+
+func (s describeScraper) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range s.descs {
+		ch <- desc
+	}
+}
+
+func TestNewCatalog(t *testing.T) {
+	css := []CommonScraper{
+		describeScraper{
+			fakeScraper: fakeScraper{name: "test_b"},
+			descs: []*prometheus.Desc{
+				NewDesc("test_catalog_z", "Z.", prometheus.GaugeValue, []string{"id"}),
+				NewDesc("test_catalog_a", "A.", prometheus.CounterValue, nil),
+			},
+		},
+		describeScraper{
+			fakeScraper: fakeScraper{name: "test_a"},
+			descs:       []*prometheus.Desc{NewDesc("test_catalog_y", "Y.", prometheus.GaugeValue, nil)},
+		},
+		// 未实现 Describe 的 Scraper 被忽略
+		fakeScraper{name: "test_c"},
+	}
+
+	var got []CatalogEntry
+	for _, e := range NewCatalog(css) {
+		if strings.HasPrefix(e.Scraper, "test_") {
+			got = append(got, e)
+		}
+	}
+	want := []CatalogEntry{
+		{Name: "test_catalog_y", Type: "gauge", Help: "Y.", Labels: []string{}, Scraper: "test_a"},
+		{Name: "test_catalog_a", Type: "counter", Help: "A.", Labels: []string{}, Scraper: "test_b"},
+		{Name: "test_catalog_z", Type: "gauge", Help: "Z.", Labels: []string{"id"}, Scraper: "test_b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewCatalog() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteCatalog(t *testing.T) {
+	entries := []CatalogEntry{
+		{Name: "test_up", Type: "gauge", Help: "Whether the target is up (1|0).", Labels: []string{}, Scraper: "exporter"},
+		{Name: "test_disk_bytes", Type: "gauge", Help: "Disk bytes.", Labels: []string{"disk_id", "host"}, Scraper: "disk_info"},
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteCatalog(&buf, "markdown", entries); err != nil {
+			t.Fatal(err)
+		}
+		want := "| Name | Type | Help | Labels | Scraper |\n" +
+			"| --- | --- | --- | --- | --- |\n" +
+			"| test_up | gauge | Whether the target is up (1\\|0). |  | exporter |\n" +
+			"| test_disk_bytes | gauge | Disk bytes. | disk_id, host | disk_info |\n"
+		if buf.String() != want {
+			t.Errorf("WriteCatalog() =\n%s\nwant\n%s", buf.String(), want)
+		}
+
+		var md bytes.Buffer
+		if err := WriteCatalog(&md, "md", entries); err != nil || md.String() != buf.String() {
+			t.Errorf("WriteCatalog(md) = %v, want the same output as markdown", err)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteCatalog(&buf, "json", entries); err != nil {
+			t.Fatal(err)
+		}
+		var got []CatalogEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("WriteCatalog() = %+v, want %+v", got, entries)
+		}
+		// 没有标签时输出空数组而不是 null
+		if !strings.Contains(buf.String(), `"labels": []`) {
+			t.Errorf("WriteCatalog() = %s, want empty labels as []", buf.String())
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := WriteCatalog(&bytes.Buffer{}, "yaml", entries); err == nil {
+			t.Error("WriteCatalog() = nil, want error for unknown format")
+		}
+	})
+}
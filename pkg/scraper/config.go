@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Config 是 Exporter 的配置文件，与具体的 Server 无关
+type Config struct {
+	// Labels 添加到 Exporter 产生的所有 Metric 中的常量标签
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// MetricRelabelConfigs 对 Exporter 产生的所有 Metric 执行的重新标记配置
+	MetricRelabelConfigs []*RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
+	// Scrapers 每个 Scraper 单独的配置，key 为 Scraper 的名称
+	Scrapers map[string]*ScraperConfig `yaml:"scrapers,omitempty"`
+	// Targets 多目标模式中的所有抓取目标，为空时只抓取通过命令行标志指定的目标
+	Targets []*TargetConfig `yaml:"targets,omitempty"`
+}
+
+// ScraperConfig 是单个 Scraper 的配置
+type ScraperConfig struct {
+	// MetricRelabelConfigs 对该 Scraper 产生的 Metric 执行的重新标记配置，设置后将会代替全局的 metric_relabel_configs
+	MetricRelabelConfigs []*RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
+	// HonorTimestamps 是否保留 Scraper 使用上游数据中的时间戳生成的 Metric 的时间戳。
+	// 上游数据的时间戳可能远早于抓取时间，超过 Prometheus 查询的回溯时间(默认 5m)后，即时查询将无法获取到这些 Metric
+	HonorTimestamps bool `yaml:"honor_timestamps,omitempty"`
+	// Filters 按名称过滤该 Scraper 抓取的资源，key 为过滤的字段，e.g. bucket。只有实现了 ConfigurableScraper 的 Scraper 支持，支持的字段由 Scraper 决定
+	Filters map[string]*NameFilter `yaml:"filters,omitempty"`
+}
+
+// TargetLabel 多目标模式中用于区分目标的标签
+const TargetLabel = "target"
+
+// TargetConfig 是多目标模式中的一个抓取目标，未设置的认证信息使用命令行标志中的值
+type TargetConfig struct {
+	// Name 目标的名称，作为 target 标签的值，为空时使用 URL
+	Name     string `yaml:"name,omitempty"`
+	URL      string `yaml:"url"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Labels 只添加到该目标的 Metric 中的常量标签，每个目标都需要设置相同名称的标签
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// TargetName 返回目标的名称
+func (t *TargetConfig) TargetName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.URL
+}
+
+// LoadConfig 从文件中加载配置，filename 为空时返回空配置
+func LoadConfig(filename string) (*Config, error) {
+	cfg := &Config{}
+	if filename == "" {
+		return cfg, nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", filename, err)
+	}
+	return cfg, nil
+}
+
+// MetricRelabelConfigsFor 返回指定 Scraper 使用的重新标记配置。
+// 若该 Scraper 单独设置了 metric_relabel_configs 则使用其自己的配置，否则使用全局配置
+func (c *Config) MetricRelabelConfigsFor(scraperName string) []*RelabelConfig {
+	if sc, ok := c.Scrapers[scraperName]; ok && sc != nil && sc.MetricRelabelConfigs != nil {
+		return sc.MetricRelabelConfigs
+	}
+	return c.MetricRelabelConfigs
+}
+
+// HonorTimestampsFor 判断是否保留指定 Scraper 产生的 Metric 的时间戳
+func (c *Config) HonorTimestampsFor(scraperName string) bool {
+	sc, ok := c.Scrapers[scraperName]
+	return ok && sc != nil && sc.HonorTimestamps
+}
+
+// validateTargets 检查所有目标的配置是否有效
+func (c *Config) validateTargets() error {
+	names := make(map[string]bool, len(c.Targets))
+	for i, t := range c.Targets {
+		if t == nil || t.URL == "" {
+			return fmt.Errorf("targets[%d]: url must be set", i)
+		}
+		if names[t.TargetName()] {
+			return fmt.Errorf("targets[%d]: duplicate target %q", i, t.TargetName())
+		}
+		names[t.TargetName()] = true
+		for k := range t.Labels {
+			if !model.LabelName(k).IsValid() || strings.HasPrefix(k, "__") || k == TargetLabel {
+				return fmt.Errorf("targets[%d]: invalid label name %q", i, k)
+			}
+			// 同名 Metric 的标签名称必须一致，所以每个目标都需要设置相同的标签
+			if _, ok := c.Targets[0].Labels[k]; !ok {
+				return fmt.Errorf("targets[%d]: label %q must be set on every target", i, k)
+			}
+		}
+		if len(t.Labels) != len(c.Targets[0].Labels) {
+			return fmt.Errorf("targets[%d]: every target must set the same labels", i)
+		}
+	}
+	return nil
+}
+
+// hasRelabelConfigs 判断是否设置了任何重新标记配置
+func (c *Config) hasRelabelConfigs() bool {
+	if len(c.MetricRelabelConfigs) > 0 {
+		return true
+	}
+	for _, sc := range c.Scrapers {
+		if sc != nil && len(sc.MetricRelabelConfigs) > 0 {
+			return true
+		}
+	}
+	return false
+}
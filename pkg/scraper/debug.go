@@ -0,0 +1,323 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// DebugOpts 调试接口所需的选项
+type DebugOpts struct {
+	// Enabled 是否开启 /debug/scrapers/<name> 接口
+	Enabled bool
+	// BearerToken 访问调试接口时需要在 Authorization 请求头中携带的 Token
+	BearerToken string
+}
+
+// AddFlag use after set Opts
+func (o *DebugOpts) AddFlag() {
+	pflag.BoolVar(&o.Enabled, "debug.scrapers", false, "Enable the /debug/scrapers/<name> endpoint, which runs one scraper and returns the raw upstream responses next to the metrics it produced. Requires --debug.bearer-token.")
+	pflag.StringVar(&o.BearerToken, "debug.bearer-token", "", "Bearer token required in the Authorization header of /debug/scrapers requests.")
+}
+
+// Validate 检查选项是否有效，开启调试接口时必须设置 Token
+func (o *DebugOpts) Validate() error {
+	if o.Enabled && o.BearerToken == "" {
+		return fmt.Errorf("--debug.bearer-token must be set when --debug.scrapers is enabled")
+	}
+	return nil
+}
+
+// DebugResult 执行一次 Scraper 的调试结果
+type DebugResult struct {
+	Scraper         string         `json:"scraper"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Error           string         `json:"error,omitempty"`
+	Requests        []DebugRequest `json:"requests"`
+	Metrics         []DebugMetric  `json:"metrics"`
+}
+
+// DebugRequest Scraper 对上游的一次请求，请求体与响应体中的敏感信息已经隐去
+type DebugRequest struct {
+	Method          string          `json:"method"`
+	Endpoint        string          `json:"endpoint"`
+	RequestBody     json.RawMessage `json:"request_body,omitempty"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Error           string          `json:"error,omitempty"`
+	Response        json.RawMessage `json:"response,omitempty"`
+}
+
+// DebugMetric Scraper 直接产生的一个 Metric，未经过常量标签、重新标记等处理
+type DebugMetric struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// DebugHandler 返回 /debug/scrapers/<name> 的 http.Handler。
+// 每次请求都会执行一次指定的 Scraper，并返回其对上游的所有请求与响应，以及产生的 Metric。
+// 多目标模式中需要通过 target 参数指定目标，e.g. /debug/scrapers/cluster_info?target=xsky-a
+func (t *Targets) DebugHandler(opts *DebugOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(auth, []byte("Bearer "+opts.BearerToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		target := r.URL.Query().Get(TargetLabel)
+		e, ok := t.Exporter(target)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown target %q, targets: %s", target, strings.Join(t.Names(), ", ")), http.StatusNotFound)
+			return
+		}
+
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		var scraper CommonScraper
+		names := make([]string, 0, len(e.scrapers))
+		for _, s := range e.scrapers {
+			names = append(names, s.Name())
+			if s.Name() == name {
+				scraper = s
+			}
+		}
+		if scraper == nil {
+			sort.Strings(names)
+			http.Error(w, fmt.Sprintf("unknown or disabled scraper %q, enabled scrapers: %s", name, strings.Join(names, ", ")), http.StatusNotFound)
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{"scraper": name, "target": target}).Info("通过调试接口执行 Scraper")
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(e.debugScrape(r.Context(), scraper)); err != nil {
+			logrus.Errorf("写入调试结果失败: %v", err)
+		}
+	})
+}
+
+// debugScrape 使用记录请求的客户端执行一次 Scraper
+func (e *Exporter) debugScrape(ctx context.Context, s CommonScraper) DebugResult {
+	result := DebugResult{Scraper: s.Name(), Requests: []DebugRequest{}, Metrics: []DebugMetric{}}
+	// 与 /metrics 中的 Scraper 共享同一个并发限制，调试请求不能绕过对上游的并发保护
+	if e.sem != nil {
+		select {
+		case e.sem <- struct{}{}:
+			defer func() { <-e.sem }()
+		case <-ctx.Done():
+			result.Error = ctx.Err().Error()
+			return result
+		}
+	}
+	client := &recordingClient{CommonClient: withContext(ctx, e.client)}
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range ch {
+			result.Metrics = append(result.Metrics, debugMetric(m))
+		}
+	}()
+
+	start := time.Now()
+	err := s.Scrape(client, ch)
+	close(ch)
+	<-done
+
+	result.DurationSeconds = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Requests = append(result.Requests, client.requests...)
+	return result
+}
+
+// recordingClient 记录 Scraper 通过它发起的每次请求与响应
+type recordingClient struct {
+	CommonClient
+
+	mu       sync.Mutex
+	requests []DebugRequest
+}
+
+// Request 实现 CommonClient 接口，Scraper 可能会并发调用
+func (c *recordingClient) Request(method string, endpoint string, reqBody io.Reader) ([]byte, error) {
+	dr := DebugRequest{Method: method, Endpoint: redactText(endpoint)}
+	if reqBody != nil {
+		b, err := io.ReadAll(reqBody)
+		if err != nil {
+			return nil, err
+		}
+		dr.RequestBody = redactBody(b)
+		reqBody = bytes.NewReader(b)
+	}
+
+	start := time.Now()
+	body, err := c.CommonClient.Request(method, endpoint, reqBody)
+	dr.DurationSeconds = time.Since(start).Seconds()
+	if err != nil {
+		dr.Error = err.Error()
+	}
+	if body != nil {
+		dr.Response = redactBody(body)
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, dr)
+	c.mu.Unlock()
+	return body, err
+}
+
+// debugMetric 将 Metric 转换为调试结果中的格式
+func debugMetric(m prometheus.Metric) DebugMetric {
+	dm := DebugMetric{Labels: map[string]string{}}
+	if md, ok := LookupDesc(m.Desc()); ok {
+		dm.Name = md.Name
+		dm.Type = md.TypeString()
+	} else {
+		dm.Name = m.Desc().String()
+	}
+
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		dm.Error = err.Error()
+		return dm
+	}
+	dm.Labels = labelPairsToMap(pb.Label)
+	switch {
+	case pb.Gauge != nil:
+		dm.Value = pb.Gauge.GetValue()
+	case pb.Counter != nil:
+		dm.Value = pb.Counter.GetValue()
+	case pb.Untyped != nil:
+		dm.Value = pb.Untyped.GetValue()
+	}
+	if pb.TimestampMs != nil {
+		ts := time.UnixMilli(pb.GetTimestampMs())
+		dm.Timestamp = &ts
+	}
+	return dm
+}
+
+// redactedValue 敏感信息替换后的值
+const redactedValue = "[REDACTED]"
+
+// sensitiveWords 字段名中表示敏感信息的单词
+var sensitiveWords = map[string]bool{
+	"token":         true,
+	"tokens":        true,
+	"password":      true,
+	"passwd":        true,
+	"secret":        true,
+	"credential":    true,
+	"credentials":   true,
+	"auth":          true,
+	"authorization": true,
+}
+
+// isSensitiveKey 判断字段名是否包含敏感信息。字段名按 _、-、. 与驼峰拆分为单词后整词匹配，
+// e.g. x_auth_token、Xms-Auth-Token、accessToken 都是敏感的，author 不是
+func isSensitiveKey(key string) bool {
+	for _, word := range keyWords(key) {
+		if sensitiveWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// keyWords 将字段名拆分为小写的单词
+func keyWords(key string) (words []string) {
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	prevLower := false
+	for _, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			prevLower = false
+			continue
+		case unicode.IsUpper(r) && prevLower:
+			flush()
+		}
+		word = append(word, unicode.ToLower(r))
+		prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	flush()
+	return words
+}
+
+// keyValueText 匹配非 JSON 格式内容中的键值对，e.g. password=xxx、"token": "xxx"
+var keyValueText = regexp.MustCompile(`([\w.-]+)("?\s*[:=]\s*"?)([^"&,\s]+)`)
+
+// redactText 隐去非 JSON 格式内容中键名敏感的值
+func redactText(s string) string {
+	return keyValueText.ReplaceAllStringFunc(s, func(kv string) string {
+		m := keyValueText.FindStringSubmatch(kv)
+		if !isSensitiveKey(m[1]) {
+			return kv
+		}
+		return m[1] + m[2] + redactedValue
+	})
+}
+
+// redactBody 隐去请求体或响应体中的敏感信息。JSON 格式的内容按字段名处理，其他内容按文本处理并作为 JSON 字符串返回
+func redactBody(b []byte) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err == nil {
+		if redacted, err := json.Marshal(redactJSON(v)); err == nil {
+			return redacted
+		}
+	}
+	s, _ := json.Marshal(redactText(string(b)))
+	return s
+}
+
+// redactJSON 递归地将字段名敏感的值替换为 redactedValue，布尔值与 null 不包含敏感信息，保持原样
+func redactJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			switch val.(type) {
+			case bool, nil:
+			default:
+				if isSensitiveKey(k) {
+					t[k] = redactedValue
+					continue
+				}
+			}
+			t[k] = redactJSON(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redactJSON(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"token", true},
+		{"access_token", true},
+		{"x_auth_token", true},
+		{"Xms-Auth-Token", true},
+		{"accessToken", true},
+		{"password", true},
+		{"auth", true},
+		{"Authorization", true},
+		{"client.secret", true},
+		{"author", false},
+		{"authority", false},
+		{"tokenizer", false},
+		{"name", false},
+		{"secretary_name", false},
+	}
+	for _, tt := range tests {
+		if got := isSensitiveKey(tt.key); got != tt.want {
+			t.Errorf("isSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "json",
+			body: `{"token":{"uuid":"abc"},"author":"bob","users":[{"password":"p","enabled":true,"auth":null}]}`,
+			want: `{"author":"bob","token":"[REDACTED]","users":[{"auth":null,"enabled":true,"password":"[REDACTED]"}]}`,
+		},
+		{
+			name: "text",
+			body: `user=bob&password=p&author=alice&access_token=t`,
+			want: `"user=bob\u0026password=[REDACTED]\u0026author=alice\u0026access_token=[REDACTED]"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactBody([]byte(tt.body))); got != tt.want {
+				t.Errorf("redactBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got, want := redactText("/api/v1/users?author=bob&token=abc"), "/api/v1/users?author=bob&token=[REDACTED]"; got != want {
+		t.Errorf("redactText() = %s, want %s", got, want)
+	}
+}
+
+// TestDebugScrapeSharesSemaphore 检查调试接口与 /metrics 共享并发限制，并发已满时等待直到请求被取消
+func TestDebugScrapeSharesSemaphore(t *testing.T) {
+	targets := NewTargets(1)
+	e := &Exporter{}
+	targets.Add("a", e)
+	targets.sem <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result := e.debugScrape(ctx, fakeScraper{name: "a"})
+	if result.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("debugScrape() error = %q, want it to wait for the semaphore until the deadline", result.Error)
+	}
+}
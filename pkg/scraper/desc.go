@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricDesc 记录了一个 Metric 的元数据。
+// prometheus.Desc 并不对外暴露 Metric 的名称、类型、标签等信息，所以在创建 Desc 时将这些信息一并保存下来，
+// 以便生成指标目录等功能可以根据 Desc 反查 Metric 的信息。
+type MetricDesc struct {
+	Name        string
+	Help        string
+	Type        prometheus.ValueType
+	Labels      []string
+	ConstLabels prometheus.Labels
+}
+
+// TypeString 返回 Metric 类型的名称，与 Prometheus 文本格式中 # TYPE 后面的内容一致
+func (md MetricDesc) TypeString() string {
+	switch md.Type {
+	case prometheus.CounterValue:
+		return "counter"
+	case prometheus.GaugeValue:
+		return "gauge"
+	default:
+		return "untyped"
+	}
+}
+
+// descs 保存所有通过 NewDesc 创建的 Desc 与其元数据的对应关系
+var descs sync.Map
+
+// NewDesc 与 prometheus.NewDesc 相同，但是需要额外指定 Metric 的类型。
+// 所有 Scraper 都应该通过该函数创建 Desc，这样才可以通过 LookupDesc 获取到 Metric 的元数据。
+func NewDesc(fqName, help string, valueType prometheus.ValueType, variableLabels []string) *prometheus.Desc {
+	return newDesc(MetricDesc{
+		Name:   fqName,
+		Help:   help,
+		Type:   valueType,
+		Labels: variableLabels,
+	})
+}
+
+// newDesc 根据元数据创建 Desc，并记录两者的对应关系
+func newDesc(md MetricDesc) *prometheus.Desc {
+	desc := prometheus.NewDesc(md.Name, md.Help, md.Labels, md.ConstLabels)
+	descs.Store(desc, md)
+	return desc
+}
+
+// recordOpts 记录通过 prometheus.NewCounter 等函数创建的 Metric 的元数据
+func recordOpts(desc *prometheus.Desc, opts prometheus.Opts, valueType prometheus.ValueType, variableLabels []string) {
+	descs.Store(desc, MetricDesc{
+		Name:        prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		Help:        opts.Help,
+		Type:        valueType,
+		Labels:      variableLabels,
+		ConstLabels: opts.ConstLabels,
+	})
+}
+
+// LookupDesc 根据 Desc 获取 Metric 的元数据。若 Desc 不是通过 NewDesc 创建的，则返回 false
+func LookupDesc(desc *prometheus.Desc) (MetricDesc, bool) {
+	v, ok := descs.Load(desc)
+	if !ok {
+		return MetricDesc{}, false
+	}
+	return v.(MetricDesc), true
+}
@@ -1,11 +1,18 @@
 package scraper
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -21,41 +28,165 @@ type Metrics struct {
 	ScrapeErrors *prometheus.CounterVec
 	Error        prometheus.Gauge
 	UP           prometheus.Gauge
+	// DroppedSeries 由于超过序列数量限制或处理标签值后重复而被丢弃的序列总数
+	DroppedSeries *prometheus.CounterVec
 }
 
-// NewMetrics 实例化 Metrics，设定本程序默认自带的一些 Metrics 的信息
-func NewMetrics() Metrics {
-	return Metrics{
-		DurationDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, Subsystem, "collector_duration_seconds"),
-			"Collector time duration.",
-			[]string{"collector"}, nil,
-		),
-		TotalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: Namespace,
-			Subsystem: Subsystem,
-			Name:      "scrapes_total",
-			Help:      "Total number of times Exporter was scraped for metrics.",
-		}),
-		ScrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Namespace: Namespace,
-			Subsystem: Subsystem,
-			Name:      "scrape_errors_total",
-			Help:      "Total number of times an error occurred scraping a Exporter.",
-		}, []string{"collector"}),
-		Error: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: Namespace,
-			Subsystem: Subsystem,
-			Name:      "last_scrape_error",
-			Help:      "Whether the last scrape of metrics from Exporter resulted in an error (1 for error, 0 for success).",
-		}),
-		UP: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: Namespace,
-			Subsystem: Subsystem,
-			Name:      "up",
-			Help:      "Whether the Exporter is up.",
+// NewMetrics 实例化 Metrics，设定本程序默认自带的一些 Metrics 的信息。constLabels 会作为常量标签添加到所有 Metrics 中
+func NewMetrics(constLabels prometheus.Labels) Metrics {
+	totalScrapesOpts := prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Subsystem:   Subsystem,
+		Name:        "scrapes_total",
+		Help:        "Total number of times Exporter was scraped for metrics.",
+		ConstLabels: constLabels,
+	}
+	scrapeErrorsOpts := prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Subsystem:   Subsystem,
+		Name:        "scrape_errors_total",
+		Help:        "Total number of times an error occurred scraping a Exporter.",
+		ConstLabels: constLabels,
+	}
+	errorOpts := prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Subsystem:   Subsystem,
+		Name:        "last_scrape_error",
+		Help:        "Whether the last scrape of metrics from Exporter resulted in an error (1 for error, 0 for success).",
+		ConstLabels: constLabels,
+	}
+	upOpts := prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Subsystem:   Subsystem,
+		Name:        "up",
+		Help:        "Whether the Exporter is up.",
+		ConstLabels: constLabels,
+	}
+	droppedSeriesOpts := prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Subsystem:   Subsystem,
+		Name:        "dropped_series_total",
+		Help:        "Total number of series dropped because a series limit was exceeded or shortening label values made them duplicates.",
+		ConstLabels: constLabels,
+	}
+
+	m := Metrics{
+		DurationDesc: newDesc(MetricDesc{
+			Name:        prometheus.BuildFQName(Namespace, Subsystem, "collector_duration_seconds"),
+			Help:        "Collector time duration.",
+			Type:        prometheus.GaugeValue,
+			Labels:      []string{"collector"},
+			ConstLabels: constLabels,
 		}),
+		TotalScrapes:  prometheus.NewCounter(totalScrapesOpts),
+		ScrapeErrors:  prometheus.NewCounterVec(scrapeErrorsOpts, []string{"collector"}),
+		Error:         prometheus.NewGauge(errorOpts),
+		UP:            prometheus.NewGauge(upOpts),
+		DroppedSeries: prometheus.NewCounterVec(droppedSeriesOpts, []string{"collector", "metric"}),
 	}
+
+	// 记录这些 Metric 的元数据，以便生成指标目录
+	recordOpts(m.TotalScrapes.Desc(), prometheus.Opts(totalScrapesOpts), prometheus.CounterValue, nil)
+	scrapeErrorsDesc := make(chan *prometheus.Desc, 1)
+	m.ScrapeErrors.Describe(scrapeErrorsDesc)
+	recordOpts(<-scrapeErrorsDesc, prometheus.Opts(scrapeErrorsOpts), prometheus.CounterValue, []string{"collector"})
+	recordOpts(m.Error.Desc(), prometheus.Opts(errorOpts), prometheus.GaugeValue, nil)
+	recordOpts(m.UP.Desc(), prometheus.Opts(upOpts), prometheus.GaugeValue, nil)
+	droppedSeriesDesc := make(chan *prometheus.Desc, 1)
+	m.DroppedSeries.Describe(droppedSeriesDesc)
+	recordOpts(<-droppedSeriesDesc, prometheus.Opts(droppedSeriesOpts), prometheus.CounterValue, []string{"collector", "metric"})
+
+	return m
+}
+
+// Describe 将本程序默认自带的所有 Metrics 的 Desc 发送到 channel(通道) 中
+func (m Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.DurationDesc
+	ch <- m.TotalScrapes.Desc()
+	m.ScrapeErrors.Describe(ch)
+	ch <- m.Error.Desc()
+	ch <- m.UP.Desc()
+	m.DroppedSeries.Describe(ch)
+}
+
+// ExporterOpts Exporter 的通用选项，与具体的 Server 无关
+type ExporterOpts struct {
+	// MaxSeriesPerMetric 一次抓取中每个 Metric 最多可以产生的序列数量，0 表示不限制
+	MaxSeriesPerMetric int
+	// MaxSeriesPerScraper 一次抓取中每个 Scraper 最多可以产生的序列数量，0 表示不限制
+	MaxSeriesPerScraper int
+	// MaxLabelValueLength 标签值的最大长度，超过该长度的标签值将根据 LabelValueOverflow 进行处理，0 表示不限制
+	MaxLabelValueLength int
+	// LabelValueOverflow 标签值过长时的处理方式，可以是 truncate 或 hash
+	LabelValueOverflow string
+
+	// ConfigFile 配置文件的路径，Config 为从该文件中加载的配置
+	ConfigFile string
+	Config     *Config
+
+	// Labels 通过命令行标志设置的常量标签，格式为 key=value
+	Labels []string
+	// ConstLabels 添加到 Exporter 产生的所有 Metric 中的常量标签，由配置文件与命令行标志中的标签合并而来
+	ConstLabels prometheus.Labels
+
+	// TargetConcurrency 多目标模式中所有目标同时执行的 Scraper 的最大数量，0 表示不限制
+	TargetConcurrency int
+}
+
+// AddFlag use after set Opts
+func (o *ExporterOpts) AddFlag() {
+	pflag.IntVar(&o.MaxSeriesPerMetric, "limit.series-per-metric", 0, "Maximum number of series a single metric may produce per scrape, excess series are dropped. 0 means no limit.")
+	pflag.IntVar(&o.MaxSeriesPerScraper, "limit.series-per-scraper", 0, "Maximum number of series a single scraper may produce per scrape, excess series are dropped. 0 means no limit.")
+	pflag.IntVar(&o.MaxLabelValueLength, "limit.label-value-length", 0, "Maximum length of a label value produced by scrapers. 0 means no limit.")
+	pflag.StringVar(&o.LabelValueOverflow, "limit.label-value-overflow", LabelValueTruncate, "How to handle label values longer than --limit.label-value-length, one of truncate or hash.")
+	pflag.StringVar(&o.ConfigFile, "config.file", "", "Path to the exporter configuration file.")
+	pflag.IntVar(&o.TargetConcurrency, "target.concurrency", 10, "Maximum number of scrapers running at the same time across all targets. 0 means no limit.")
+	pflag.StringArrayVar(&o.Labels, "label", nil, "Constant label added to every exported series, in key=value format. May be repeated, overrides labels from the configuration file.")
+}
+
+// LoadConfig 根据 ConfigFile 加载配置文件，并将配置文件与命令行标志中的常量标签合并。需要在解析命令行标志之后调用
+func (o *ExporterOpts) LoadConfig() (err error) {
+	if o.Config, err = LoadConfig(o.ConfigFile); err != nil {
+		return err
+	}
+
+	o.ConstLabels = prometheus.Labels{}
+	for k, v := range o.Config.Labels {
+		o.ConstLabels[k] = v
+	}
+	for _, l := range o.Labels {
+		k, v, ok := strings.Cut(l, "=")
+		if !ok {
+			return fmt.Errorf("invalid label %q, must be in key=value format", l)
+		}
+		o.ConstLabels[k] = v
+	}
+	for k := range o.ConstLabels {
+		if !model.LabelName(k).IsValid() || strings.HasPrefix(k, "__") {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	if err := o.Config.validateTargets(); err != nil {
+		return err
+	}
+	if _, ok := o.ConstLabels[TargetLabel]; ok && len(o.Config.Targets) > 0 {
+		return fmt.Errorf("label %q is reserved for targets", TargetLabel)
+	}
+	return nil
+}
+
+// ForTarget 返回多目标模式中指定目标使用的选项，除了常量标签中添加了目标的标签以外，与 o 完全相同
+func (o *ExporterOpts) ForTarget(t *TargetConfig) *ExporterOpts {
+	targetOpts := *o
+	targetOpts.ConstLabels = prometheus.Labels{}
+	for k, v := range o.ConstLabels {
+		targetOpts.ConstLabels[k] = v
+	}
+	for k, v := range t.Labels {
+		targetOpts.ConstLabels[k] = v
+	}
+	targetOpts.ConstLabels[TargetLabel] = t.TargetName()
+	return &targetOpts
 }
 
 // Exporter 实现了 prometheus.Collector，其中包含了很多 Metric。
@@ -64,38 +195,96 @@ type Exporter struct {
 	client   CommonClient
 	scrapers []CommonScraper
 	metrics  Metrics
+	opts     *ExporterOpts
+
+	// 记录每个 Scraper 上一次输出丢弃序列日志的时间，用于限制日志输出频率
+	dropLogMutex sync.Mutex
+	dropLogTimes map[string]time.Time
+	// 缓存重新标记后生成的 Desc
+	relabelDescs sync.Map
+	// 缓存添加常量标签后生成的 Desc，key 为 Scraper 创建的原始 Desc
+	labeledDescs sync.Map
+
+	// 记录目标与每个 Scraper 最近的抓取情况，用于状态页面
+	statsMutex   sync.RWMutex
+	targetStats  targetStats
+	scraperStats map[string]*scraperStats
+
+	// sem 限制同时执行的 Scraper 数量，为 nil 时不限制
+	sem chan struct{}
 }
 
 // NewExporter 实例化 Exporter
-func NewExporter(cc CommonClient, css []CommonScraper) *Exporter {
+func NewExporter(cc CommonClient, css []CommonScraper, opts *ExporterOpts) *Exporter {
+	if opts == nil {
+		opts = &ExporterOpts{}
+	}
+	if opts.Config == nil {
+		opts.Config = &Config{}
+	}
 	return &Exporter{
-		client:   cc,
-		scrapers: css,
-		metrics:  NewMetrics(),
+		client:       cc,
+		scrapers:     css,
+		metrics:      NewMetrics(opts.ConstLabels),
+		opts:         opts,
+		dropLogTimes: make(map[string]time.Time),
+		scraperStats: make(map[string]*scraperStats),
 	}
 }
 
 // Describe 实现 Collector 接口的方法
+// 除了本程序默认自带的 Metrics 以外，还会将所有 Scraper 声明的 Desc 一并发送，这样注册器就可以对所有 Metric 进行检查。
+// 只要有一个 Scraper 没有实现 DescribableScraper 接口，就无法保证 Collect 时产生的 Metric 都已经被描述，
+// 此时不发送任何 Desc，让 Exporter 作为 unchecked collector 注册。
+// 设置了 metric_relabel_configs 时，Metric 的名称与标签都可能发生变化，同样作为 unchecked collector 注册。
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- e.metrics.TotalScrapes.Desc()
-	e.metrics.ScrapeErrors.Describe(ch)
-	ch <- e.metrics.Error.Desc()
-	ch <- e.metrics.UP.Desc()
+	if e.opts.Config.hasRelabelConfigs() {
+		logrus.Info("设置了 metric_relabel_configs，Exporter 将作为 unchecked collector 注册")
+		return
+	}
+
+	describers := make([]DescribableScraper, 0, len(e.scrapers))
+	for _, scraper := range e.scrapers {
+		ds, ok := scraper.(DescribableScraper)
+		if !ok {
+			logrus.WithField("scraper", scraper.Name()).Warn("Scraper 未实现 Describe 方法，Exporter 将作为 unchecked collector 注册")
+			return
+		}
+		describers = append(describers, ds)
+	}
+
+	e.metrics.Describe(ch)
+	labeled, done := e.labelDescs(ch)
+	defer done()
+	for _, ds := range describers {
+		ds.Describe(labeled)
+	}
 }
 
 // Collect 实现 Collector 接口的方法
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.CollectContext(context.Background(), ch)
+}
+
+// CollectContext 与 Collect 相同，ctx 中的 Trace 信息会传递给每个 Scraper 以及对上游的每次请求。实现了 ContextCollector 接口
+func (e *Exporter) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	// 本程序默认自带的 Metrics 使用全局的 metric_relabel_configs 进行重新标记
+	self, done := e.relabel(e.opts.Config.MetricRelabelConfigs, ch)
+	defer done()
+
 	// 将 scrape() 方法引进来，用来在实现 Collect 接口后，调用 prometheus 功能可以操作 scrape() 中相关的 Metrics
-	e.scrape(ch)
+	e.scrape(ctx, self, ch)
 
-	ch <- e.metrics.TotalScrapes
-	e.metrics.ScrapeErrors.Collect(ch)
-	ch <- e.metrics.Error
-	ch <- e.metrics.UP
+	self <- e.metrics.TotalScrapes
+	e.metrics.ScrapeErrors.Collect(self)
+	self <- e.metrics.Error
+	self <- e.metrics.UP
+	e.metrics.DroppedSeries.Collect(self)
 }
 
 // scrape 调用每个已经注册的 Scraper(抓取器) 执行其代码中定义的抓取行为。
-func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
+// 本程序默认自带的 Metrics 发送到 self 中，Scraper 产生的 Metric 经过处理后发送到 ch 中。
+func (e *Exporter) scrape(ctx context.Context, self, ch chan<- prometheus.Metric) {
 	// 每执行一次 scrape，TotalScraple 这个 Metrci 的值加一，用于统计从启动到现在采集了多少次
 	e.metrics.TotalScrapes.Inc()
 
@@ -104,8 +293,15 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 
 	// 检验目标服务器是否正常，每次执行 Collect 都会检查
 	// 然后为 UP 和 Error 这俩 Metrics 设置值。
-	if pong, err := e.client.Ping(); pong != true || err != nil {
-		logrus.WithFields(logrus.Fields{"ping error": "健康检查失败"}).Error(err)
+	pingCtx, span := Tracer().Start(ctx, "ping")
+	pong, err := e.client.Ping()
+	if err == nil && !pong {
+		err = fmt.Errorf("ping failed")
+	}
+	EndSpan(span, err)
+	e.recordPing(err)
+	if err != nil {
+		Logger(pingCtx).WithFields(logrus.Fields{"ping error": "健康检查失败"}).Error(err)
 		e.metrics.UP.Set(0)
 		e.metrics.Error.Set(1)
 		return
@@ -114,7 +310,7 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 	e.metrics.Error.Set(0)
 
 	// 对应第一个 scrapeTime，显示 scrapeDurationDesc 这个 Metric 的标签为 reach 的时间。也就是检验目标服务器状态总共花了多长时间
-	ch <- prometheus.MustNewConstMetric(e.metrics.DurationDesc, prometheus.GaugeValue, time.Since(scrapeTime).Seconds(), "reach")
+	self <- prometheus.MustNewConstMetric(e.metrics.DurationDesc, prometheus.GaugeValue, time.Since(scrapeTime).Seconds(), "reach")
 
 	var wg sync.WaitGroup
 	defer wg.Wait()
@@ -128,19 +324,38 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 		// go 协程，同时执行所有 Scraper
 		go func(scraper CommonScraper) {
 			defer wg.Done()
+			// 多目标模式中所有目标共享同一个并发限制
+			if e.sem != nil {
+				e.sem <- struct{}{}
+				defer func() { <-e.sem }()
+			}
 			// 第二个 scrapeTime,开始统计 scrape 指标的耗时
 			label := scraper.Name()
 			scrapeTime := time.Now()
+			// Scraper 产生的 Metric 需要先处理时间戳并添加常量标签，再经过重新标记与序列数量限制，最后发送到 ch 中
+			limited, limitDone := e.limit(label, ch)
+			relabeled, relabelDone := e.relabel(e.opts.Config.MetricRelabelConfigsFor(label), limited)
+			labeled, labelDone := e.label(relabeled)
+			stripped, stripDone := e.stripTimestamps(label, labeled)
+			// 每个 Scraper 的执行都是一个子 Span，Scraper 通过绑定了该 Span 的客户端向上游发起请求
+			scrapeCtx, span := Tracer().Start(ctx, "scrape "+label, trace.WithAttributes(attribute.String("scraper", label)))
 			// 执行 Scrape 操作，也就是执行每个 Scraper 中的 Scrape() 方法，由于这些自定义的 Scraper 都实现了 Scraper 接口
 			// 所以 Scrape 这个调用，就是调用的当前循环体中，从 e.scrapers 数组中取到的值，也就是 collector.ScrapeCluster{} 这些结构体
-			if err := scraper.Scrape(e.client, ch); err != nil {
-				logrus.WithField("scraper", scraper.Name()).Error(err)
+			err := scraper.Scrape(withContext(scrapeCtx, e.client), stripped)
+			EndSpan(span, err)
+			e.recordScrape(label, time.Since(scrapeTime), err)
+			stripDone()
+			labelDone()
+			relabelDone()
+			limitDone()
+			if err != nil {
+				Logger(scrapeCtx).WithField("scraper", scraper.Name()).Error(err)
 				e.metrics.ScrapeErrors.WithLabelValues(label).Inc()
 				e.metrics.Error.Set(1)
 			}
 			// 对应第二个 scrapeTime，scrapeDurationDesc 这个 Metric，用于显示抓取标签为 label(这是变量) 指标所消耗的时间
 			// 其实就是统计每个 Scraper 执行所消耗的时间
-			ch <- prometheus.MustNewConstMetric(e.metrics.DurationDesc, prometheus.GaugeValue, time.Since(scrapeTime).Seconds(), label)
+			self <- prometheus.MustNewConstMetric(e.metrics.DurationDesc, prometheus.GaugeValue, time.Since(scrapeTime).Seconds(), label)
 		}(scraper)
 	}
 }
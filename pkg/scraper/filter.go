@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// NameFilter 按名称过滤 Scraper 抓取的资源，表达式与 metric_relabel_configs 中的 regex 一样首尾锚定。
+// Include 不为空时只保留名称与其中任意一个表达式匹配的资源，之后再丢弃名称与 Exclude 中任意一个表达式匹配的资源
+type NameFilter struct {
+	Include []Regexp `yaml:"include,omitempty"`
+	Exclude []Regexp `yaml:"exclude,omitempty"`
+}
+
+// Match 判断名称为 name 的资源是否应该保留。f 为 nil 时保留所有资源
+func (f *NameFilter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	return !matchAny(f.Exclude, name)
+}
+
+// matchAny 判断 s 是否与 res 中的任意一个表达式匹配
+func matchAny(res []Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter 返回指定字段的过滤器，未设置时返回 nil
+func (c *ScraperConfig) Filter(field string) *NameFilter {
+	if c == nil {
+		return nil
+	}
+	return c.Filters[field]
+}
+
+// CheckFilters 检查是否设置了 fields 以外的字段的过滤器，Scraper 应该在 Configure 中使用其支持的字段调用
+func (c *ScraperConfig) CheckFilters(fields ...string) error {
+	if c == nil {
+		return nil
+	}
+	var unknown []string
+	for field := range c.Filters {
+		if !slices.Contains(fields, field) {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unsupported filters %s, supported filters: %s", strings.Join(unknown, ", "), strings.Join(fields, ", "))
+	}
+	return nil
+}
+
+// ConfigurableScraper 是 CommonScraper 的可选扩展。
+// 实现了该接口的 Scraper 可以使用配置文件中自己的配置，e.g. 通过 filters 过滤抓取的资源
+type ConfigurableScraper interface {
+	CommonScraper
+
+	// Configure 返回使用 cfg 配置后的 Scraper，cfg 为 nil 表示配置文件中没有该 Scraper 的配置
+	Configure(cfg *ScraperConfig) (CommonScraper, error)
+}
+
+// ConfigureScrapers 使用配置文件中每个 Scraper 自己的配置，返回配置后的 Scraper，应该在 NewExporter 之前调用。
+// 未实现 ConfigurableScraper 接口的 Scraper 保持不变，为其设置了 filters 时返回错误
+func ConfigureScrapers(css []CommonScraper, cfg *Config) ([]CommonScraper, error) {
+	configured := make([]CommonScraper, 0, len(css))
+	for _, s := range css {
+		var sc *ScraperConfig
+		if cfg != nil {
+			sc = cfg.Scrapers[s.Name()]
+		}
+
+		cs, ok := s.(ConfigurableScraper)
+		if !ok {
+			if sc != nil && len(sc.Filters) > 0 {
+				return nil, fmt.Errorf("scrapers.%s: scraper does not support filters", s.Name())
+			}
+			configured = append(configured, s)
+			continue
+		}
+
+		s, err := cs.Configure(sc)
+		if err != nil {
+			return nil, fmt.Errorf("scrapers.%s: %w", cs.Name(), err)
+		}
+		configured = append(configured, s)
+	}
+	return configured, nil
+}
@@ -0,0 +1,163 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// HealthOpts 健康检查与就绪检查所需的选项
+type HealthOpts struct {
+	// PingInterval 后台检查目标是否可以连接的间隔
+	PingInterval time.Duration
+}
+
+// AddFlag use after set Opts
+func (o *HealthOpts) AddFlag() {
+	pflag.DurationVar(&o.PingInterval, "health.ping-interval", 30*time.Second, "Interval between two background pings of the target, /-/ready reports not ready when the last ping failed.")
+}
+
+// Health 记录 Exporter 的运行状态，为 /-/healthy 与 /-/ready 提供数据。
+// healthy 表示进程存活；ready 表示最近一次后台 Ping 成功。配置文件或者客户端有问题时程序在启动时就会退出，不需要单独检查。
+// 多目标模式中，单个目标不可用时依然是就绪的，此时应该通过该目标的 up 判断其状态，只有所有目标都不可用时才不是就绪的
+type Health struct {
+	opts      *HealthOpts
+	targets   *Targets
+	startTime time.Time
+
+	mu sync.RWMutex
+	// lastPings 每个目标最近一次后台 Ping 的结果，key 为目标的名称，尚未执行时不存在
+	lastPings map[string]*HealthCheck
+}
+
+// HealthCheck 单项检查的结果
+type HealthCheck struct {
+	OK        bool       `json:"ok"`
+	Error     string     `json:"error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// HealthStatus /-/healthy 与 /-/ready 响应体的内容
+type HealthStatus struct {
+	Status        string                 `json:"status"`
+	UptimeSeconds float64                `json:"uptime_seconds"`
+	Checks        map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// NewHealth 实例化 Health，targets 为需要在后台 Ping 的所有目标
+func NewHealth(opts *HealthOpts, targets *Targets) *Health {
+	return &Health{
+		opts:      opts,
+		targets:   targets,
+		startTime: time.Now(),
+		lastPings: make(map[string]*HealthCheck),
+	}
+}
+
+// Run 按照 PingInterval 的间隔在后台 Ping 所有目标，直到 ctx 被取消。启动后会立即执行一次
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		h.ping(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ping 并行 Ping 所有目标并记录结果
+func (h *Health) ping(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i, e := range h.targets.exporters {
+		wg.Add(1)
+		go func(name string, cc CommonClient) {
+			defer wg.Done()
+
+			pingCtx, span := Tracer().Start(ctx, "background ping")
+			pong, err := cc.Ping()
+			if err == nil && !pong {
+				err = fmt.Errorf("ping failed")
+			}
+			EndSpan(span, err)
+
+			now := time.Now()
+			check := &HealthCheck{OK: err == nil, CheckedAt: &now}
+			if err != nil {
+				check.Error = err.Error()
+				Logger(pingCtx).WithField("target", name).Warnf("后台健康检查失败: %v", err)
+			}
+
+			h.mu.Lock()
+			h.lastPings[name] = check
+			h.mu.Unlock()
+		}(h.targets.names[i], e.client)
+	}
+	wg.Wait()
+}
+
+// Ready 返回就绪检查的结果
+func (h *Health) Ready() (ready bool, status HealthStatus) {
+	checks := map[string]HealthCheck{}
+
+	// 只有一个目标时检查项为 ping，多目标模式中每个目标的检查项为 ping:<目标名称>
+	names := h.targets.names
+	h.mu.RLock()
+	for _, name := range names {
+		key := "ping"
+		if len(names) > 1 {
+			key = "ping:" + name
+		}
+		check := HealthCheck{Error: "no ping has completed yet"}
+		if lastPing, ok := h.lastPings[name]; ok {
+			check = *lastPing
+		}
+		checks[key] = check
+		ready = ready || check.OK
+	}
+	h.mu.RUnlock()
+
+	status = HealthStatus{Status: "ready", UptimeSeconds: time.Since(h.startTime).Seconds(), Checks: checks}
+	if !ready {
+		status.Status = "not ready"
+	}
+	return ready, status
+}
+
+// HealthyHandler 返回 /-/healthy 的 http.Handler，进程存活时总是返回 200
+func (h *Health) HealthyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthStatus(w, http.StatusOK, HealthStatus{Status: "healthy", UptimeSeconds: time.Since(h.startTime).Seconds()})
+	})
+}
+
+// ReadyHandler 返回 /-/ready 的 http.Handler，就绪时返回 200，否则返回 503
+func (h *Health) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ready, status := h.Ready()
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeHealthStatus(w, code, status)
+	})
+}
+
+// writeHealthStatus 将 status 以 JSON 格式写入响应体
+func writeHealthStatus(w http.ResponseWriter, code int, status HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logrus.Errorf("写入健康检查响应失败: %v", err)
+	}
+}
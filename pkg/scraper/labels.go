@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// labelDesc 返回添加了 Exporter 常量标签的 Desc。
+// Scraper 中的 Desc 在程序初始化时就已经创建好了，无法在创建时添加通过命令行标志设置的常量标签，
+// 所以需要根据 Desc 的元数据重新创建一个 Desc。无法获取元数据的 Desc 将原样返回。
+func (e *Exporter) labelDesc(desc *prometheus.Desc) *prometheus.Desc {
+	if len(e.opts.ConstLabels) == 0 {
+		return desc
+	}
+	if labeled, ok := e.labeledDescs.Load(desc); ok {
+		return labeled.(*prometheus.Desc)
+	}
+
+	md, ok := LookupDesc(desc)
+	if !ok {
+		return desc
+	}
+	constLabels := prometheus.Labels{}
+	for k, v := range md.ConstLabels {
+		constLabels[k] = v
+	}
+	for k, v := range e.opts.ConstLabels {
+		constLabels[k] = v
+	}
+	md.ConstLabels = constLabels
+
+	labeled, _ := e.labeledDescs.LoadOrStore(desc, newDesc(md))
+	return labeled.(*prometheus.Desc)
+}
+
+// labelDescs 返回一个用于替代 ch 的通道，发送到该通道的 Desc 添加常量标签后再发送到 ch 中。
+// 所有 Desc 发送完成后需要调用返回的 done 函数
+func (e *Exporter) labelDescs(ch chan<- *prometheus.Desc) (labeled chan<- *prometheus.Desc, done func()) {
+	in := make(chan *prometheus.Desc)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for desc := range in {
+			ch <- e.labelDesc(desc)
+		}
+	}()
+
+	return in, func() {
+		close(in)
+		wg.Wait()
+	}
+}
+
+// label 返回一个用于替代 ch 的通道，发送到该通道的 Metric 添加常量标签后，再发送到 ch 中。
+// 所有 Metric 发送完成后需要调用返回的 done 函数
+func (e *Exporter) label(ch chan<- prometheus.Metric) (labeled chan<- prometheus.Metric, done func()) {
+	if len(e.opts.ConstLabels) == 0 {
+		return ch, func() {}
+	}
+
+	in := make(chan prometheus.Metric)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range in {
+			ch <- e.labelMetric(m)
+		}
+	}()
+
+	return in, func() {
+		close(in)
+		wg.Wait()
+	}
+}
+
+// labelMetric 为 Metric 添加常量标签，无法添加时原样返回
+func (e *Exporter) labelMetric(m prometheus.Metric) prometheus.Metric {
+	desc := e.labelDesc(m.Desc())
+	if desc == m.Desc() {
+		return m
+	}
+	s, err := parseMetric(m)
+	if err != nil {
+		return m
+	}
+	nm, err := s.toMetric(desc)
+	if err != nil {
+		logrus.WithField("metric", s.md.Name).Errorf("添加常量标签失败: %v", err)
+		return m
+	}
+	return nm
+}
@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// 标签值过长时的处理方式
+const (
+	// LabelValueTruncate 将标签值截断为最大长度
+	LabelValueTruncate = "truncate"
+	// LabelValueHash 将标签值替换为其哈希值
+	LabelValueHash = "hash"
+)
+
+// dropLogInterval 同一个 Scraper 丢弃序列时，两次日志输出的最小间隔
+const dropLogInterval = time.Minute
+
+// seriesLimiter 用于在一次抓取中限制某个 Scraper 产生的序列数量，并处理过长的标签值，防止上游返回的数据导致序列数量暴增
+type seriesLimiter struct {
+	opts      *ExporterOpts
+	total     int
+	perMetric map[string]int
+	// seen 记录本次抓取中已经发送的序列，只在限制标签值长度时使用
+	seen map[string]struct{}
+	// dropped 记录本次抓取中每个 Metric 被丢弃的序列数量
+	dropped map[string]int
+}
+
+// admit 判断 Metric 是否可以发送，可以发送时返回处理过标签值的 Metric
+func (l *seriesLimiter) admit(m prometheus.Metric) (prometheus.Metric, bool) {
+	name := m.Desc().String()
+	if md, ok := LookupDesc(m.Desc()); ok {
+		name = md.Name
+	}
+
+	// 截断后不同的标签值可能变为相同的值，e.g. billing-2024-a 与 billing-2024-b 截断为 8 个字符后都是 billing-，
+	// 相同的序列会导致整个 Gather 失败，所以处理标签值之后丢弃重复的序列
+	var key string
+	if l.opts.MaxLabelValueLength > 0 {
+		m = l.shortenLabelValues(m)
+		var err error
+		if key, err = seriesKey(name, m); err == nil {
+			if _, ok := l.seen[key]; ok {
+				l.dropped[name]++
+				return nil, false
+			}
+		}
+	}
+
+	if (l.opts.MaxSeriesPerScraper > 0 && l.total >= l.opts.MaxSeriesPerScraper) ||
+		(l.opts.MaxSeriesPerMetric > 0 && l.perMetric[name] >= l.opts.MaxSeriesPerMetric) {
+		l.dropped[name]++
+		return nil, false
+	}
+	l.total++
+	l.perMetric[name]++
+	if key != "" {
+		l.seen[key] = struct{}{}
+	}
+	return m, true
+}
+
+// seriesKey 返回由 Metric 名称与标签组成的序列标识
+func seriesKey(name string, m prometheus.Metric) (string, error) {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	for _, lp := range pb.Label {
+		b.WriteByte('\xff')
+		b.WriteString(lp.GetName())
+		b.WriteByte('\xff')
+		b.WriteString(lp.GetValue())
+	}
+	return b.String(), nil
+}
+
+// shortenLabelValues 根据配置截断或哈希过长的标签值
+func (l *seriesLimiter) shortenLabelValues(m prometheus.Metric) prometheus.Metric {
+	s, err := parseMetric(m)
+	if err != nil {
+		return m
+	}
+
+	changed := false
+	for name, value := range s.labels {
+		if utf8.RuneCountInString(value) <= l.opts.MaxLabelValueLength {
+			continue
+		}
+		s.labels[name] = l.shortenLabelValue(value)
+		changed = true
+	}
+	if !changed {
+		return m
+	}
+
+	nm, err := s.toMetric(m.Desc())
+	if err != nil {
+		return m
+	}
+	return nm
+}
+
+// shortenLabelValue 处理单个过长的标签值
+func (l *seriesLimiter) shortenLabelValue(value string) string {
+	if l.opts.LabelValueOverflow == LabelValueHash {
+		h := fnv.New64a()
+		h.Write([]byte(value))
+		return fmt.Sprintf("%016x", h.Sum64())
+	}
+	runes := []rune(value)
+	return string(runes[:l.opts.MaxLabelValueLength])
+}
+
+// limit 返回一个用于替代 ch 的通道，Scraper 发送到该通道的 Metric 经过序列数量限制及标签值处理后，再发送到 ch 中。
+// Scraper 执行完成后需要调用返回的 done 函数，以便统计被丢弃的序列。
+func (e *Exporter) limit(scraperName string, ch chan<- prometheus.Metric) (limited chan<- prometheus.Metric, done func()) {
+	if e.opts.MaxSeriesPerMetric <= 0 && e.opts.MaxSeriesPerScraper <= 0 && e.opts.MaxLabelValueLength <= 0 {
+		return ch, func() {}
+	}
+
+	l := &seriesLimiter{
+		opts:      e.opts,
+		perMetric: make(map[string]int),
+		seen:      make(map[string]struct{}),
+		dropped:   make(map[string]int),
+	}
+	in := make(chan prometheus.Metric)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range in {
+			if m, ok := l.admit(m); ok {
+				ch <- m
+			}
+		}
+	}()
+
+	return in, func() {
+		close(in)
+		wg.Wait()
+		e.recordDropped(scraperName, l.dropped)
+	}
+}
+
+// recordDropped 统计被丢弃的序列，并按照 dropLogInterval 的间隔输出日志
+func (e *Exporter) recordDropped(scraperName string, dropped map[string]int) {
+	if len(dropped) == 0 {
+		return
+	}
+	for name, count := range dropped {
+		e.metrics.DroppedSeries.WithLabelValues(scraperName, name).Add(float64(count))
+	}
+
+	e.dropLogMutex.Lock()
+	defer e.dropLogMutex.Unlock()
+	if time.Since(e.dropLogTimes[scraperName]) < dropLogInterval {
+		return
+	}
+	e.dropLogTimes[scraperName] = time.Now()
+	logrus.WithFields(logrus.Fields{
+		"scraper": scraperName,
+		"dropped": dropped,
+	}).Warn("Scraper 产生的序列数量超过限制或处理标签值后出现重复的序列，这些序列已被丢弃")
+}
@@ -0,0 +1,231 @@
+package scraper
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLimit(t *testing.T) {
+	diskDesc := NewDesc("test_limit_disk_bytes", "Test disk bytes.", prometheus.GaugeValue, []string{"disk"})
+	hostDesc := NewDesc("test_limit_host_up", "Test host up.", prometheus.GaugeValue, []string{"host"})
+	scrape := func(ch chan<- prometheus.Metric) {
+		for _, disk := range []string{"sda", "sdb", "sdc"} {
+			ch <- prometheus.MustNewConstMetric(diskDesc, prometheus.GaugeValue, 1, disk)
+		}
+		for _, host := range []string{"node1", "node2"} {
+			ch <- prometheus.MustNewConstMetric(hostDesc, prometheus.GaugeValue, 1, host)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts ExporterOpts
+		// series 为每个 Metric 保留的序列数量
+		series map[string]int
+		// dropped 为 dropped_series_total 中每个 Metric 的值
+		dropped map[string]float64
+	}{
+		{
+			name:   "no limit",
+			series: map[string]int{"test_limit_disk_bytes": 3, "test_limit_host_up": 2},
+		},
+		{
+			name:    "per metric",
+			opts:    ExporterOpts{MaxSeriesPerMetric: 2},
+			series:  map[string]int{"test_limit_disk_bytes": 2, "test_limit_host_up": 2},
+			dropped: map[string]float64{"test_limit_disk_bytes": 1},
+		},
+		{
+			name:    "per scraper",
+			opts:    ExporterOpts{MaxSeriesPerScraper: 3},
+			series:  map[string]int{"test_limit_disk_bytes": 3},
+			dropped: map[string]float64{"test_limit_host_up": 2},
+		},
+		{
+			name:    "per metric and per scraper",
+			opts:    ExporterOpts{MaxSeriesPerMetric: 1, MaxSeriesPerScraper: 1},
+			series:  map[string]int{"test_limit_disk_bytes": 1},
+			dropped: map[string]float64{"test_limit_disk_bytes": 2, "test_limit_host_up": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			e := NewExporter(fakeClient{}, []CommonScraper{fakeScraper{name: "fake", scrape: scrape}}, &opts)
+			mfs := gather(t, e)
+
+			series := make(map[string]int)
+			for name, mf := range mfs {
+				if name == "test_limit_disk_bytes" || name == "test_limit_host_up" {
+					series[name] = len(mf.Metric)
+				}
+			}
+			if !reflect.DeepEqual(series, tt.series) {
+				t.Errorf("series = %v, want %v", series, tt.series)
+			}
+			if got := droppedSeries(mfs); !reflect.DeepEqual(got, tt.dropped) {
+				t.Errorf("dropped_series_total = %v, want %v", got, tt.dropped)
+			}
+		})
+	}
+}
+
+// TestLimitPerScrape 检查序列数量按每次抓取分别计算，dropped_series_total 在多次抓取之间累加
+func TestLimitPerScrape(t *testing.T) {
+	desc := NewDesc("test_limit_per_scrape", "Test series.", prometheus.GaugeValue, []string{"id"})
+	scrape := func(ch chan<- prometheus.Metric) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "1")
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "2")
+	}
+	e := NewExporter(fakeClient{}, []CommonScraper{fakeScraper{name: "fake", scrape: scrape}}, &ExporterOpts{MaxSeriesPerMetric: 1})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(e)
+
+	for i := 1; i <= 2; i++ {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := make(map[string]*dto.MetricFamily)
+		for _, mf := range mfs {
+			byName[mf.GetName()] = mf
+		}
+		if got := len(byName["test_limit_per_scrape"].GetMetric()); got != 1 {
+			t.Errorf("scrape %d: %d series, want 1", i, got)
+		}
+		if got, want := droppedSeries(byName), map[string]float64{"test_limit_per_scrape": float64(i)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("scrape %d: dropped_series_total = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestLimitLabelValueLength(t *testing.T) {
+	desc := NewDesc("test_limit_label_value", "Test label value.", prometheus.GaugeValue, []string{"path", "pool"})
+	scrape := func(ch chan<- prometheus.Metric) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "/data/bucket/object", "p1")
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "存储池一号", "p2")
+	}
+
+	tests := []struct {
+		name     string
+		overflow string
+		want     []string
+	}{
+		{
+			name:     "truncate",
+			overflow: LabelValueTruncate,
+			// 按字符而不是字节计算长度
+			want: []string{"/data", "存储池一号"},
+		},
+		{
+			name:     "hash",
+			overflow: LabelValueHash,
+			// 未超过长度的标签值不会被哈希
+			want: []string{"7619e287efcb0f09", "存储池一号"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &ExporterOpts{MaxLabelValueLength: 5, LabelValueOverflow: tt.overflow}
+			e := NewExporter(fakeClient{}, []CommonScraper{fakeScraper{name: "fake", scrape: scrape}}, opts)
+			mf := gather(t, e)["test_limit_label_value"]
+			if mf == nil {
+				t.Fatal("test_limit_label_value not collected")
+			}
+			var paths []string
+			for _, m := range mf.Metric {
+				labels := labelsOf(m)
+				paths = append(paths, labels["path"])
+				// 未超过长度的标签值保持不变
+				if labels["pool"] != "p1" && labels["pool"] != "p2" {
+					t.Errorf("pool = %q, want it unchanged", labels["pool"])
+				}
+			}
+			sort.Strings(paths)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(paths, want) {
+				t.Errorf("path values = %q, want %q", paths, want)
+			}
+		})
+	}
+}
+
+// TestLimitLabelValueCollision 检查截断后相同的标签值不会产生重复的序列导致 Gather 失败，重复的序列被丢弃并计入 dropped_series_total
+func TestLimitLabelValueCollision(t *testing.T) {
+	desc := NewDesc("test_limit_collision", "Test collision.", prometheus.GaugeValue, []string{"bucket"})
+	scrape := func(ch chan<- prometheus.Metric) {
+		for _, bucket := range []string{"billing-2024-a", "billing-2024-b", "billing-", "logs"} {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, bucket)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    ExporterOpts
+		want    []string
+		dropped map[string]float64
+	}{
+		{
+			name:    "truncate",
+			opts:    ExporterOpts{MaxLabelValueLength: 8, LabelValueOverflow: LabelValueTruncate},
+			want:    []string{"billing-", "logs"},
+			dropped: map[string]float64{"test_limit_collision": 2},
+		},
+		{
+			// 重复的序列不占用序列数量限制
+			name:    "truncate with per metric limit",
+			opts:    ExporterOpts{MaxLabelValueLength: 8, LabelValueOverflow: LabelValueTruncate, MaxSeriesPerMetric: 2},
+			want:    []string{"billing-", "logs"},
+			dropped: map[string]float64{"test_limit_collision": 2},
+		},
+		{
+			name: "hash",
+			opts: ExporterOpts{MaxLabelValueLength: 8, LabelValueOverflow: LabelValueHash},
+			want: []string{"billing-", "logs", seriesHash("billing-2024-a"), seriesHash("billing-2024-b")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			e := NewExporter(fakeClient{}, []CommonScraper{fakeScraper{name: "fake", scrape: scrape}}, &opts)
+			// gather 在出现重复的序列时失败
+			mfs := gather(t, e)
+			var buckets []string
+			for _, m := range mfs["test_limit_collision"].GetMetric() {
+				buckets = append(buckets, labelsOf(m)["bucket"])
+			}
+			sort.Strings(buckets)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(buckets, want) {
+				t.Errorf("buckets = %q, want %q", buckets, want)
+			}
+			if got := droppedSeries(mfs); !reflect.DeepEqual(got, tt.dropped) {
+				t.Errorf("dropped_series_total = %v, want %v", got, tt.dropped)
+			}
+		})
+	}
+}
+
+// seriesHash 返回标签值过长时 hash 处理方式得到的值
+func seriesHash(value string) string {
+	return (&seriesLimiter{opts: &ExporterOpts{LabelValueOverflow: LabelValueHash}}).shortenLabelValue(value)
+}
+
+// droppedSeries 返回 dropped_series_total 中每个 Metric 的值
+func droppedSeries(mfs map[string]*dto.MetricFamily) map[string]float64 {
+	mf := mfs[prometheus.BuildFQName(Namespace, Subsystem, "dropped_series_total")]
+	if mf == nil {
+		return nil
+	}
+	dropped := make(map[string]float64)
+	for _, m := range mf.Metric {
+		dropped[labelsOf(m)["metric"]] = m.GetCounter().GetValue()
+	}
+	return dropped
+}
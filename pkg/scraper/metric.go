@@ -0,0 +1,74 @@
+package scraper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sample 是从 prometheus.Metric 中解析出来的一个样本。
+// 对 Scraper 产生的 Metric 进行二次处理(e.g. 修改标签值)时，需要先将 Metric 解析为 sample，处理完成后再转换回 Metric。
+type sample struct {
+	md MetricDesc
+	// labels 只包含可变标签
+	labels    map[string]string
+	value     float64
+	timestamp *int64
+}
+
+// parseMetric 将 Metric 解析为 sample。
+// 只有通过 NewDesc 创建，且类型为 Counter、Gauge 或 Untyped 的 Metric 才可以被解析。
+func parseMetric(m prometheus.Metric) (sample, error) {
+	md, ok := LookupDesc(m.Desc())
+	if !ok {
+		return sample{}, fmt.Errorf("desc is not created by scraper.NewDesc: %v", m.Desc())
+	}
+
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		return sample{}, err
+	}
+
+	s := sample{
+		md:        md,
+		labels:    make(map[string]string, len(md.Labels)),
+		timestamp: pb.TimestampMs,
+	}
+	switch {
+	case pb.Gauge != nil:
+		s.value = pb.Gauge.GetValue()
+	case pb.Counter != nil:
+		s.value = pb.Counter.GetValue()
+	case pb.Untyped != nil:
+		s.value = pb.Untyped.GetValue()
+	default:
+		return sample{}, fmt.Errorf("unsupported metric type: %v", m.Desc())
+	}
+
+	for _, lp := range pb.Label {
+		if _, isConst := md.ConstLabels[lp.GetName()]; isConst {
+			continue
+		}
+		s.labels[lp.GetName()] = lp.GetValue()
+	}
+	return s, nil
+}
+
+// toMetric 根据 desc 将 sample 转换回 Metric，desc 的可变标签必须与 sample.md.Labels 一致
+func (s sample) toMetric(desc *prometheus.Desc) (prometheus.Metric, error) {
+	labelValues := make([]string, 0, len(s.md.Labels))
+	for _, name := range s.md.Labels {
+		labelValues = append(labelValues, s.labels[name])
+	}
+
+	m, err := prometheus.NewConstMetric(desc, s.md.Type, s.value, labelValues...)
+	if err != nil {
+		return nil, err
+	}
+	if s.timestamp != nil {
+		m = prometheus.NewMetricWithTimestamp(time.UnixMilli(*s.timestamp), m)
+	}
+	return m, nil
+}
@@ -0,0 +1,192 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// OTLPOpts 通过 OTLP/HTTP 推送 Metric 所需的选项
+type OTLPOpts struct {
+	// Endpoint OTLP/HTTP 接收 Metric 的地址，为空时不推送，e.g. http://otel-collector:4318/v1/metrics
+	Endpoint string
+	Interval time.Duration
+	Timeout  time.Duration
+	// Headers 推送时添加的请求头，格式为 key=value
+	Headers []string
+}
+
+// AddFlag use after set Opts
+func (o *OTLPOpts) AddFlag() {
+	pflag.StringVar(&o.Endpoint, "otlp.metrics-endpoint", "", "OTLP/HTTP endpoint to push metrics to, e.g. http://localhost:4318/v1/metrics. Pushing is disabled when empty.")
+	pflag.DurationVar(&o.Interval, "otlp.push-interval", 30*time.Second, "Interval between two OTLP metric pushes.")
+	pflag.DurationVar(&o.Timeout, "otlp.timeout", 10*time.Second, "Timeout on OTLP push requests.")
+	pflag.StringArrayVar(&o.Headers, "otlp.header", nil, "Header added to OTLP push requests, in key=value format. May be repeated.")
+}
+
+// OTLPPusher 将 Exporter 产生的 Metric 转换为 OTLP 格式，并通过 OTLP/HTTP(JSON 编码) 推送到 OpenTelemetry Collector
+type OTLPPusher struct {
+	opts   *OTLPOpts
+	client *http.Client
+	// resource 推送时携带的资源属性
+	resource map[string]string
+	// startTime Counter 类型 Metric 的起始时间
+	startTime time.Time
+}
+
+// NewOTLPPusher 实例化 OTLPPusher。exporterName 与 target 将作为资源属性随 Metric 一起推送，
+// 多目标模式中 target 为空，每个 Metric 通过 target 标签区分目标
+func NewOTLPPusher(opts *OTLPOpts, exporterName string, target string) *OTLPPusher {
+	resource := map[string]string{"service.name": exporterName}
+	if target != "" {
+		resource["target"] = target
+	}
+	return &OTLPPusher{
+		opts:      opts,
+		client:    &http.Client{Timeout: opts.Timeout},
+		resource:  resource,
+		startTime: time.Now(),
+	}
+}
+
+// Run 按照 Interval 的间隔从 gatherer 中获取 Metric 并推送，直到 ctx 被取消
+func (p *OTLPPusher) Run(ctx context.Context, gatherer prometheus.Gatherer) {
+	logrus.WithField("endpoint", p.opts.Endpoint).Info("开始通过 OTLP 推送 Metric")
+	Poll(ctx, gatherer, p.opts.Interval, "otlp", p.Push)
+}
+
+// Push 将 Metric 推送到 Endpoint
+func (p *OTLPPusher) Push(ctx context.Context, mfs []*dto.MetricFamily, ts time.Time) error {
+	body, err := protojson.Marshal(p.request(mfs, ts))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, h := range p.opts.Headers {
+		k, v, ok := strings.Cut(h, "=")
+		if !ok {
+			return fmt.Errorf("invalid OTLP header %q, must be in key=value format", h)
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error pushing metrics to %s http-statuscode: %s, body: %s", p.opts.Endpoint, resp.Status, respBody)
+	}
+	logrus.Debugf("OTLP 推送成功，共 %v 个 Metric", len(mfs))
+	return nil
+}
+
+// request 将 MetricFamily 转换为 OTLP 的 ExportMetricsServiceRequest
+func (p *OTLPPusher) request(mfs []*dto.MetricFamily, ts time.Time) *collectormetrics.ExportMetricsServiceRequest {
+	metrics := make([]*metricspb.Metric, 0, len(mfs))
+	for _, mf := range mfs {
+		points := make([]*metricspb.NumberDataPoint, 0, len(mf.Metric))
+		for _, pm := range mf.Metric {
+			var value float64
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				value = pm.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = pm.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = pm.GetUntyped().GetValue()
+			default:
+				logrus.WithField("metric", mf.GetName()).Debug("OTLP 推送暂不支持该类型的 Metric")
+				continue
+			}
+
+			pointTime := ts
+			if pm.TimestampMs != nil {
+				pointTime = time.UnixMilli(pm.GetTimestampMs())
+			}
+			point := &metricspb.NumberDataPoint{
+				Attributes:   otlpAttributes(labelPairsToMap(pm.Label)),
+				TimeUnixNano: uint64(pointTime.UnixNano()),
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+			}
+			if mf.GetType() == dto.MetricType_COUNTER {
+				point.StartTimeUnixNano = uint64(p.startTime.UnixNano())
+			}
+			points = append(points, point)
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		m := &metricspb.Metric{
+			Name:        mf.GetName(),
+			Description: mf.GetHelp(),
+		}
+		// Counter 对应 OTLP 中单调递增的累积 Sum，其余类型对应 Gauge
+		if mf.GetType() == dto.MetricType_COUNTER {
+			m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				DataPoints:             points,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+			}}
+		} else {
+			m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+		}
+		metrics = append(metrics, m)
+	}
+
+	return &collectormetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: otlpAttributes(p.resource)},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: "github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+// labelPairsToMap 将标签对转换为 map
+func labelPairsToMap(lps []*dto.LabelPair) map[string]string {
+	labels := make(map[string]string, len(lps))
+	for _, lp := range lps {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	return labels
+}
+
+// otlpAttributes 将 map 转换为按 key 排序的 OTLP 属性列表
+func otlpAttributes(m map[string]string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(m))
+	for k, v := range m {
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+		})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
@@ -0,0 +1,172 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// otlpReceiver 启动一个 OTLP/HTTP 接收端，使用 opentelemetry-proto 中的类型解析推送的请求
+func otlpReceiver(t *testing.T, received chan<- *collectormetrics.ExportMetricsServiceRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req := &collectormetrics.ExportMetricsServiceRequest{}
+		if err := protojson.Unmarshal(body, req); err != nil {
+			t.Errorf("protojson.Unmarshal() = %v, body: %s", err, body)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- req
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOTLPPush(t *testing.T) {
+	received := make(chan *collectormetrics.ExportMetricsServiceRequest, 1)
+	srv := otlpReceiver(t, received)
+
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_pool_used_bytes", Help: "Pool used bytes."}, []string{"pool"})
+	gauge.WithLabelValues("p1").Set(42)
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests."})
+	counter.Add(3)
+	reg.MustRegister(gauge, counter, timestampCollector{desc: prometheus.NewDesc("test_sample", "Test sample.", nil, nil)})
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewOTLPPusher(&OTLPOpts{Endpoint: srv.URL, Timeout: time.Second}, "xsky_exporter", "xsky-01")
+	ts := time.Unix(1700000100, 0)
+	if err := p.Push(context.Background(), mfs, ts); err != nil {
+		t.Fatalf("Push() = %v", err)
+	}
+	req := <-received
+
+	if len(req.ResourceMetrics) != 1 {
+		t.Fatalf("got %d resource metrics, want 1", len(req.ResourceMetrics))
+	}
+	rm := req.ResourceMetrics[0]
+	resource := make(map[string]string)
+	for _, kv := range rm.GetResource().GetAttributes() {
+		resource[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	if resource["service.name"] != "xsky_exporter" || resource["target"] != "xsky-01" || len(resource) != 2 {
+		t.Errorf("resource attributes = %v", resource)
+	}
+
+	metrics := make(map[string]*metricspb.Metric)
+	for _, m := range rm.GetScopeMetrics()[0].GetMetrics() {
+		metrics[m.GetName()] = m
+	}
+
+	g := metrics["test_pool_used_bytes"].GetGauge()
+	if g == nil || len(g.DataPoints) != 1 {
+		t.Fatalf("test_pool_used_bytes = %v, want a gauge with one point", metrics["test_pool_used_bytes"])
+	}
+	gp := g.DataPoints[0]
+	if gp.GetAsDouble() != 42 || gp.GetTimeUnixNano() != uint64(ts.UnixNano()) || gp.GetStartTimeUnixNano() != 0 {
+		t.Errorf("gauge point = %v", gp)
+	}
+	if len(gp.Attributes) != 1 || gp.Attributes[0].GetKey() != "pool" || gp.Attributes[0].GetValue().GetStringValue() != "p1" {
+		t.Errorf("gauge attributes = %v", gp.Attributes)
+	}
+	if metrics["test_pool_used_bytes"].GetDescription() != "Pool used bytes." {
+		t.Errorf("gauge description = %q", metrics["test_pool_used_bytes"].GetDescription())
+	}
+
+	// Counter 对应单调递增的累积 Sum
+	s := metrics["test_requests_total"].GetSum()
+	if s == nil || len(s.DataPoints) != 1 {
+		t.Fatalf("test_requests_total = %v, want a sum with one point", metrics["test_requests_total"])
+	}
+	if !s.IsMonotonic || s.AggregationTemporality != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Errorf("sum is_monotonic = %v, temporality = %v", s.IsMonotonic, s.AggregationTemporality)
+	}
+	sp := s.DataPoints[0]
+	if sp.GetAsDouble() != 3 || sp.GetTimeUnixNano() != uint64(ts.UnixNano()) || sp.GetStartTimeUnixNano() != uint64(p.startTime.UnixNano()) {
+		t.Errorf("sum point = %v", sp)
+	}
+
+	// 带有上游时间戳的 Metric 使用该时间戳
+	tp := metrics["test_sample"].GetGauge().GetDataPoints()
+	if len(tp) != 1 || tp[0].GetTimeUnixNano() != uint64(time.Unix(1700000000, 0).UnixNano()) {
+		t.Errorf("test_sample points = %v, want the sample timestamp", tp)
+	}
+}
+
+// TestOTLPPushSpecialValues 检查 NaN 与 Inf 按照 proto3 JSON 的规则编码，接收端可以正常解析
+func TestOTLPPushSpecialValues(t *testing.T) {
+	received := make(chan *collectormetrics.ExportMetricsServiceRequest, 1)
+	srv := otlpReceiver(t, received)
+
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_ratio", Help: "Ratio."}, []string{"case"})
+	gauge.WithLabelValues("inf").Set(math.Inf(1))
+	gauge.WithLabelValues("nan").Set(math.NaN())
+	reg.MustRegister(gauge)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewOTLPPusher(&OTLPOpts{Endpoint: srv.URL, Timeout: time.Second}, "xsky_exporter", "")
+	if err := p.Push(context.Background(), mfs, time.Now()); err != nil {
+		t.Fatalf("Push() = %v", err)
+	}
+	req := <-received
+	for _, kv := range req.ResourceMetrics[0].GetResource().GetAttributes() {
+		if kv.GetKey() == "target" {
+			t.Errorf("target resource attribute set in multi-target mode")
+		}
+	}
+	points := req.ResourceMetrics[0].GetScopeMetrics()[0].GetMetrics()[0].GetGauge().GetDataPoints()
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want 2", len(points))
+	}
+	for _, point := range points {
+		v := point.GetAsDouble()
+		switch point.Attributes[0].GetValue().GetStringValue() {
+		case "inf":
+			if !math.IsInf(v, 1) {
+				t.Errorf("inf point = %v, want +Inf", v)
+			}
+		case "nan":
+			if !math.IsNaN(v) {
+				t.Errorf("nan point = %v, want NaN", v)
+			}
+		}
+	}
+}
+
+func TestOTLPPushError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	p := NewOTLPPusher(&OTLPOpts{Endpoint: srv.URL, Timeout: time.Second}, "xsky_exporter", "")
+	if err := p.Push(context.Background(), nil, time.Now()); err == nil {
+		t.Error("Push() = nil, want error for 400 response")
+	}
+}
@@ -0,0 +1,40 @@
+package scraper
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// PollHandler 处理一次后台抓取得到的所有 Metric，ts 为本次抓取的时间
+type PollHandler func(ctx context.Context, mfs []*dto.MetricFamily, ts time.Time) error
+
+// Poll 按照 interval 的间隔在后台执行 gatherer.Gather()，并将结果交给 handler 处理，直到 ctx 被取消。
+// 用于不通过 Prometheus 抓取，而是由 Exporter 主动推送 Metric 的场景。启动后会立即执行一次。
+func Poll(ctx context.Context, gatherer prometheus.Gatherer, interval time.Duration, name string, handler PollHandler) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ts := time.Now()
+		mfs, err := gatherer.Gather()
+		if err != nil {
+			// Gather 出现错误时依然会返回尽可能多的 Metric
+			logrus.WithField("poller", name).Errorf("获取 Metric 时出现错误: %v", err)
+		}
+		if len(mfs) > 0 {
+			if err := handler(ctx, mfs, ts); err != nil {
+				logrus.WithField("poller", name).Errorf("处理 Metric 失败: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// PushgatewayOpts 将 Metric 推送到 Pushgateway 所需的选项
+type PushgatewayOpts struct {
+	// URL Pushgateway 的地址，设置后 Exporter 只执行一次抓取，推送完成后退出
+	URL string
+	// Job 分组键中的 job，为空时使用 Exporter 的名称
+	Job      string
+	Username string
+	Password string
+}
+
+// AddFlag use after set Opts
+func (o *PushgatewayOpts) AddFlag() {
+	pflag.StringVar(&o.URL, "push.gateway-url", "", "Pushgateway URL. When set, the enabled scrapers run once, the result is pushed and the exporter exits with status 1 if any scraper failed, or 2 if pushing failed.")
+	pflag.StringVar(&o.Job, "push.job", "", "Job name used in the Pushgateway grouping key. Defaults to the exporter name.")
+	pflag.StringVar(&o.Username, "push.username", "", "Username for Pushgateway basic auth.")
+	pflag.StringVar(&o.Password, "push.password", "", "Password for Pushgateway basic auth.")
+}
+
+// PushOnce 从 gatherer 中获取一次 Metric 并推送到 Pushgateway，grouping 为 job 以外的分组键。
+// scrapeOK 表示本次抓取是否成功，即目标是否存活且所有 Scraper 都没有出现错误。
+func PushOnce(opts *PushgatewayOpts, gatherer prometheus.Gatherer, grouping map[string]string) (scrapeOK bool, err error) {
+	mfs, gatherErr := gatherer.Gather()
+	if gatherErr != nil {
+		logrus.Errorf("获取 Metric 时出现错误: %v", gatherErr)
+	}
+	scrapeOK = gatherErr == nil && scrapeSucceeded(mfs)
+	// Pushgateway 会拒绝带有时间戳的 Metric，所以即使 Scraper 设置了 honor_timestamps，推送时也要去掉时间戳
+	if n := clearTimestamps(mfs); n > 0 {
+		logrus.Debugf("推送到 Pushgateway 之前去掉了 %v 个 Metric 的时间戳", n)
+	}
+
+	// 推送已经获取到的 Metric，而不是让 Pusher 再执行一次抓取
+	pusher := push.New(opts.URL, opts.Job).Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return mfs, nil
+	}))
+	for name, value := range grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	if opts.Username != "" {
+		pusher = pusher.BasicAuth(opts.Username, opts.Password)
+	}
+
+	if err := pusher.Push(); err != nil {
+		return scrapeOK, err
+	}
+	logrus.WithField("url", opts.URL).Infof("推送到 Pushgateway 成功，共 %v 个 Metric", len(mfs))
+	return scrapeOK, nil
+}
+
+// clearTimestamps 去掉 mfs 中所有 Metric 的时间戳，返回去掉时间戳的 Metric 的数量
+func clearTimestamps(mfs []*dto.MetricFamily) (n int) {
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			if m.TimestampMs != nil {
+				m.TimestampMs = nil
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// scrapeSucceeded 根据本程序默认自带的 up 与 last_scrape_error 判断本次抓取是否成功
+func scrapeSucceeded(mfs []*dto.MetricFamily) bool {
+	up := prometheus.BuildFQName(Namespace, Subsystem, "up")
+	lastScrapeError := prometheus.BuildFQName(Namespace, Subsystem, "last_scrape_error")
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			switch mf.GetName() {
+			case up:
+				if m.GetGauge().GetValue() != 1 {
+					return false
+				}
+			case lastScrapeError:
+				if m.GetGauge().GetValue() != 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// timestampCollector 产生一个带有上游时间戳的 Metric，模拟设置了 honor_timestamps 的 Scraper
+type timestampCollector struct {
+	desc *prometheus.Desc
+}
+
+func (c timestampCollector) Describe(ch chan<- *prometheus.Desc) { ch <- c.desc }
+
+func (c timestampCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.NewMetricWithTimestamp(time.Unix(1700000000, 0), prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1))
+}
+
+// TestPushOnceStripsTimestamps 检查推送到 Pushgateway 的 Metric 不带时间戳，否则 Pushgateway 会拒绝本次推送
+func TestPushOnceStripsTimestamps(t *testing.T) {
+	var pushed []*dto.MetricFamily
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			mf := &dto.MetricFamily{}
+			if err := dec.Decode(mf); err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Errorf("decoding pushed metrics: %v", err)
+				}
+				break
+			}
+			pushed = append(pushed, mf)
+		}
+		for _, mf := range pushed {
+			for _, m := range mf.Metric {
+				if m.TimestampMs != nil {
+					http.Error(w, "pushed metrics must not have timestamps", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(timestampCollector{desc: prometheus.NewDesc("test_sample", "Test sample.", nil, nil)})
+
+	if _, err := PushOnce(&PushgatewayOpts{URL: srv.URL, Job: "test"}, reg, nil); err != nil {
+		t.Fatalf("PushOnce() = %v", err)
+	}
+	if len(pushed) != 1 || pushed[0].GetName() != "test_sample" {
+		t.Fatalf("pushed %v, want test_sample", pushed)
+	}
+}
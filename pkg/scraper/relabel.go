@@ -0,0 +1,290 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// RelabelAction 是重新标记时执行的动作，与 Prometheus 的 relabel_configs 语义一致
+type RelabelAction string
+
+const (
+	// RelabelReplace 将 regex 与 source_labels 拼接后的值进行匹配，匹配成功时将 target_label 的值设置为 replacement
+	RelabelReplace RelabelAction = "replace"
+	// RelabelKeep 丢弃 source_labels 拼接后的值与 regex 不匹配的序列
+	RelabelKeep RelabelAction = "keep"
+	// RelabelDrop 丢弃 source_labels 拼接后的值与 regex 匹配的序列
+	RelabelDrop RelabelAction = "drop"
+	// RelabelLabelDrop 删除名称与 regex 匹配的标签
+	RelabelLabelDrop RelabelAction = "labeldrop"
+	// RelabelLabelKeep 删除名称与 regex 不匹配的标签
+	RelabelLabelKeep RelabelAction = "labelkeep"
+	// RelabelLabelMap 将名称与 regex 匹配的标签复制为以 replacement 命名的标签
+	RelabelLabelMap RelabelAction = "labelmap"
+)
+
+// Regexp 是在 YAML 中使用的正则表达式，与 Prometheus 一样，表达式的首尾会被自动锚定
+type Regexp struct {
+	*regexp.Regexp
+	original string
+}
+
+// NewRegexp 创建一个首尾锚定的正则表达式
+func NewRegexp(s string) (Regexp, error) {
+	re, err := regexp.Compile("^(?:" + s + ")$")
+	return Regexp{Regexp: re, original: s}, err
+}
+
+// MustNewRegexp 与 NewRegexp 相同，但是出现错误时会 panic
+func MustNewRegexp(s string) Regexp {
+	re, err := NewRegexp(s)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler 接口
+func (re *Regexp) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	r, err := NewRegexp(s)
+	if err != nil {
+		return err
+	}
+	*re = r
+	return nil
+}
+
+// MarshalYAML 实现 yaml.Marshaler 接口
+func (re Regexp) MarshalYAML() (interface{}, error) {
+	return re.original, nil
+}
+
+// String 返回未锚定的原始表达式
+func (re Regexp) String() string {
+	return re.original
+}
+
+// RelabelConfig 重新标记的配置，字段含义与 Prometheus 的 metric_relabel_configs 一致
+type RelabelConfig struct {
+	SourceLabels []string      `yaml:"source_labels,flow,omitempty"`
+	Separator    string        `yaml:"separator,omitempty"`
+	Regex        Regexp        `yaml:"regex,omitempty"`
+	TargetLabel  string        `yaml:"target_label,omitempty"`
+	Replacement  string        `yaml:"replacement,omitempty"`
+	Action       RelabelAction `yaml:"action,omitempty"`
+}
+
+// DefaultRelabelConfig 重新标记配置的默认值
+var DefaultRelabelConfig = RelabelConfig{
+	Separator:   ";",
+	Regex:       MustNewRegexp("(.*)"),
+	Replacement: "$1",
+	Action:      RelabelReplace,
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler 接口，未设置的字段使用默认值，并检查配置是否合法
+func (c *RelabelConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultRelabelConfig
+	type plain RelabelConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Regex.Regexp == nil {
+		c.Regex = MustNewRegexp("")
+	}
+	switch c.Action {
+	case RelabelReplace:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("relabel configuration for %s action requires 'target_label' value", c.Action)
+		}
+	case RelabelKeep, RelabelDrop:
+		if len(c.SourceLabels) == 0 {
+			return fmt.Errorf("relabel configuration for %s action requires 'source_labels' value", c.Action)
+		}
+	case RelabelLabelDrop, RelabelLabelKeep, RelabelLabelMap:
+	default:
+		return fmt.Errorf("unknown relabel action %q", c.Action)
+	}
+	return nil
+}
+
+// Relabel 按顺序使用 cfgs 处理标签集合，标签集合中的 __name__ 为 Metric 名称。
+// 返回处理后的标签集合，若序列需要被丢弃，则返回 nil
+func Relabel(labels map[string]string, cfgs ...*RelabelConfig) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	for _, cfg := range cfgs {
+		if out = relabel(out, cfg); out == nil {
+			return nil
+		}
+	}
+	return out
+}
+
+// relabel 使用单个配置处理标签集合
+func relabel(labels map[string]string, cfg *RelabelConfig) map[string]string {
+	values := make([]string, 0, len(cfg.SourceLabels))
+	for _, ln := range cfg.SourceLabels {
+		values = append(values, labels[ln])
+	}
+	val := strings.Join(values, cfg.Separator)
+
+	switch cfg.Action {
+	case RelabelDrop:
+		if cfg.Regex.MatchString(val) {
+			return nil
+		}
+	case RelabelKeep:
+		if !cfg.Regex.MatchString(val) {
+			return nil
+		}
+	case RelabelReplace:
+		indexes := cfg.Regex.FindStringSubmatchIndex(val)
+		if indexes == nil {
+			break
+		}
+		target := string(cfg.Regex.ExpandString([]byte{}, cfg.TargetLabel, val, indexes))
+		res := string(cfg.Regex.ExpandString([]byte{}, cfg.Replacement, val, indexes))
+		if res == "" {
+			delete(labels, target)
+			break
+		}
+		labels[target] = res
+	case RelabelLabelDrop:
+		for ln := range labels {
+			if cfg.Regex.MatchString(ln) {
+				delete(labels, ln)
+			}
+		}
+	case RelabelLabelKeep:
+		for ln := range labels {
+			if ln != metricNameLabel && !cfg.Regex.MatchString(ln) {
+				delete(labels, ln)
+			}
+		}
+	case RelabelLabelMap:
+		mapped := make(map[string]string)
+		for ln, lv := range labels {
+			if cfg.Regex.MatchString(ln) {
+				mapped[cfg.Regex.ReplaceAllString(ln, cfg.Replacement)] = lv
+			}
+		}
+		for ln, lv := range mapped {
+			labels[ln] = lv
+		}
+	}
+	return labels
+}
+
+// metricNameLabel 标签集合中表示 Metric 名称的标签
+const metricNameLabel = "__name__"
+
+// relabelDescKey 是重新标记后生成的 Desc 在缓存中的 key
+type relabelDescKey struct {
+	orig   *prometheus.Desc
+	name   string
+	labels string
+}
+
+// relabeler 用于对 Metric 执行重新标记，并缓存重新标记后生成的 Desc
+type relabeler struct {
+	cfgs  []*RelabelConfig
+	descs *sync.Map
+}
+
+// process 对 Metric 执行重新标记，若 Metric 需要被丢弃则返回 false
+func (r relabeler) process(m prometheus.Metric) (prometheus.Metric, bool) {
+	s, err := parseMetric(m)
+	if err != nil {
+		// 无法解析的 Metric 原样发送
+		return m, true
+	}
+
+	labels := make(map[string]string, len(s.labels)+len(s.md.ConstLabels)+1)
+	for k, v := range s.md.ConstLabels {
+		labels[k] = v
+	}
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	labels[metricNameLabel] = s.md.Name
+
+	labels = Relabel(labels, r.cfgs...)
+	if labels == nil {
+		return nil, false
+	}
+
+	name := labels[metricNameLabel]
+	delete(labels, metricNameLabel)
+	labelNames := make([]string, 0, len(labels))
+	for ln, lv := range labels {
+		// 与 Prometheus 一样，值为空的标签等同于不存在
+		if lv == "" {
+			delete(labels, ln)
+			continue
+		}
+		labelNames = append(labelNames, ln)
+	}
+	sort.Strings(labelNames)
+
+	// 重新标记后的标签集合中的所有标签都作为可变标签
+	md := MetricDesc{
+		Name:   name,
+		Help:   s.md.Help,
+		Type:   s.md.Type,
+		Labels: labelNames,
+	}
+	// 不同的 Metric 重新标记后可能具有相同的名称与标签，Help 等元数据来自原始 Metric，所以缓存的 key 需要包含原始 Desc
+	key := relabelDescKey{orig: m.Desc(), name: name, labels: strings.Join(labelNames, "\xff")}
+	desc, ok := r.descs.Load(key)
+	if !ok {
+		desc, _ = r.descs.LoadOrStore(key, newDesc(md))
+	}
+
+	s.md = md
+	s.labels = labels
+	nm, err := s.toMetric(desc.(*prometheus.Desc))
+	if err != nil {
+		logrus.WithField("metric", name).Errorf("重新标记后的 Metric 不合法: %v", err)
+		return nil, false
+	}
+	return nm, true
+}
+
+// relabel 返回一个用于替代 ch 的通道，发送到该通道的 Metric 经过 cfgs 重新标记后，再发送到 ch 中。
+// 所有 Metric 发送完成后需要调用返回的 done 函数
+func (e *Exporter) relabel(cfgs []*RelabelConfig, ch chan<- prometheus.Metric) (relabeled chan<- prometheus.Metric, done func()) {
+	if len(cfgs) == 0 {
+		return ch, func() {}
+	}
+
+	r := relabeler{cfgs: cfgs, descs: &e.relabelDescs}
+	in := make(chan prometheus.Metric)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range in {
+			if m, ok := r.process(m); ok {
+				ch <- m
+			}
+		}
+	}()
+
+	return in, func() {
+		close(in)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v2"
+)
+
+// mustRelabelConfigs 从 YAML 中解析重新标记配置，与配置文件中的 metric_relabel_configs 相同
+func mustRelabelConfigs(t *testing.T, s string) []*RelabelConfig {
+	t.Helper()
+	var cfgs []*RelabelConfig
+	if err := yaml.UnmarshalStrict([]byte(s), &cfgs); err != nil {
+		t.Fatalf("parsing relabel configs: %v", err)
+	}
+	return cfgs
+}
+
+func TestRelabel(t *testing.T) {
+	input := map[string]string{
+		metricNameLabel: "xsky_disk_used_bytes",
+		"disk_id":       "1",
+		"host_name":     "node1",
+		"serial_number": "S1",
+	}
+	tests := []struct {
+		name string
+		cfgs string
+		want map[string]string
+	}{
+		{
+			name: "replace with defaults",
+			cfgs: `[{source_labels: [host_name], target_label: node}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1", "host_name": "node1", "serial_number": "S1", "node": "node1"},
+		},
+		{
+			name: "replace with capture groups and separator",
+			cfgs: `[{source_labels: [host_name, disk_id], separator: "/", regex: "node(.*)/(.*)", target_label: disk, replacement: "$1-$2"}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1", "host_name": "node1", "serial_number": "S1", "disk": "1-1"},
+		},
+		{
+			name: "replace without match keeps labels",
+			cfgs: `[{source_labels: [host_name], regex: "other", target_label: node}]`,
+			want: input,
+		},
+		{
+			name: "replace with empty value removes the target",
+			cfgs: `[{source_labels: [missing], regex: "", target_label: serial_number, replacement: ""}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1", "host_name": "node1"},
+		},
+		{
+			name: "rename metric",
+			cfgs: `[{source_labels: [__name__], regex: "xsky_(.*)", target_label: __name__, replacement: "storage_$1"}]`,
+			want: map[string]string{metricNameLabel: "storage_disk_used_bytes", "disk_id": "1", "host_name": "node1", "serial_number": "S1"},
+		},
+		{
+			name: "keep matching series",
+			cfgs: `[{source_labels: [host_name], regex: "node.*", action: keep}]`,
+			want: input,
+		},
+		{
+			name: "keep drops series that do not match",
+			cfgs: `[{source_labels: [host_name], regex: "node2", action: keep}]`,
+			want: nil,
+		},
+		{
+			name: "drop matching series",
+			cfgs: `[{source_labels: [__name__], regex: "xsky_disk_.*", action: drop}]`,
+			want: nil,
+		},
+		{
+			name: "drop is anchored",
+			cfgs: `[{source_labels: [__name__], regex: "disk", action: drop}]`,
+			want: input,
+		},
+		{
+			name: "labeldrop",
+			cfgs: `[{regex: "serial_.*", action: labeldrop}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1", "host_name": "node1"},
+		},
+		{
+			name: "labelkeep keeps the metric name",
+			cfgs: `[{regex: "disk_id", action: labelkeep}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1"},
+		},
+		{
+			name: "labelmap",
+			cfgs: `[{regex: "(.*)_name", replacement: "${1}", action: labelmap}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1", "host_name": "node1", "serial_number": "S1", "host": "node1"},
+		},
+		{
+			name: "configs are applied in order",
+			cfgs: `[{regex: "host_name", action: labeldrop}, {source_labels: [host_name], regex: "", target_label: host_missing, replacement: "yes"}]`,
+			want: map[string]string{metricNameLabel: "xsky_disk_used_bytes", "disk_id": "1", "serial_number": "S1", "host_missing": "yes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Relabel(input, mustRelabelConfigs(t, tt.cfgs)...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Relabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if len(input) != 4 || input["serial_number"] != "S1" {
+		t.Errorf("Relabel() modified its input: %v", input)
+	}
+}
+
+func TestRelabelConfigValidation(t *testing.T) {
+	tests := []struct {
+		cfgs string
+		err  string
+	}{
+		{`[{source_labels: [a]}]`, "requires 'target_label'"},
+		{`[{action: keep}]`, "requires 'source_labels'"},
+		{`[{action: drop}]`, "requires 'source_labels'"},
+		{`[{action: hashmod}]`, "unknown relabel action"},
+		{`[{regex: "(", action: labeldrop}]`, "missing closing )"},
+	}
+	for _, tt := range tests {
+		var cfgs []*RelabelConfig
+		err := yaml.UnmarshalStrict([]byte(tt.cfgs), &cfgs)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parsing %s: error = %v, want it to contain %q", tt.cfgs, err, tt.err)
+		}
+	}
+}
+
+// TestRelabelerHelpPerOriginalDesc 检查两个不同的 Metric 重新标记为相同的名称与标签时，各自保留原始 Metric 的 Help
+func TestRelabelerHelpPerOriginalDesc(t *testing.T) {
+	read := NewDesc("test_read_iops", "Read operations per second.", prometheus.GaugeValue, []string{"pool"})
+	write := NewDesc("test_write_iops", "Write operations per second.", prometheus.GaugeValue, []string{"pool"})
+	r := relabeler{
+		cfgs:  mustRelabelConfigs(t, `[{source_labels: [__name__], regex: "test_.*", target_label: __name__, replacement: "test_iops"}]`),
+		descs: &sync.Map{},
+	}
+
+	for _, tt := range []struct {
+		desc *prometheus.Desc
+		help string
+	}{
+		{read, "Read operations per second."},
+		{write, "Write operations per second."},
+		{read, "Read operations per second."},
+	} {
+		m, ok := r.process(prometheus.MustNewConstMetric(tt.desc, prometheus.GaugeValue, 1, "p1"))
+		if !ok {
+			t.Fatal("process() dropped the metric")
+		}
+		md, ok := LookupDesc(m.Desc())
+		if !ok {
+			t.Fatal("relabeled desc is not registered")
+		}
+		if md.Name != "test_iops" || md.Help != tt.help {
+			t.Errorf("relabeled desc = %s %q, want test_iops %q", md.Name, md.Help, tt.help)
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		if got := labelsOf(&pb); !reflect.DeepEqual(got, map[string]string{"pool": "p1"}) {
+			t.Errorf("relabeled labels = %v", got)
+		}
+	}
+}
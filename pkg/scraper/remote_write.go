@@ -0,0 +1,405 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteOpts 通过 Prometheus remote-write 协议发送 Metric 所需的选项
+type RemoteWriteOpts struct {
+	// URL remote-write 的地址，为空时不发送，e.g. http://prometheus:9090/api/v1/write
+	URL      string
+	Interval time.Duration
+	Timeout  time.Duration
+	// 认证信息，设置了 BearerToken 时优先使用 BearerToken
+	Username    string
+	Password    string
+	BearerToken string
+	// QueueCapacity 发送失败时最多缓存的批次数量，超过后丢弃最早的批次
+	QueueCapacity int
+	// MaxRetries 每次发送时，单个批次失败后的最大重试次数
+	MaxRetries int
+	// BufferDir 缓存待发送批次的目录，为空时只缓存在内存中。设置后，程序重启后可以继续发送未发送成功的批次
+	BufferDir string
+}
+
+// AddFlag use after set Opts
+func (o *RemoteWriteOpts) AddFlag() {
+	pflag.StringVar(&o.URL, "remote-write.url", "", "Prometheus remote-write URL to send metrics to, e.g. http://prometheus:9090/api/v1/write. Sending is disabled when empty.")
+	pflag.DurationVar(&o.Interval, "remote-write.interval", 30*time.Second, "Interval between two scrapes sent with remote-write.")
+	pflag.DurationVar(&o.Timeout, "remote-write.timeout", 10*time.Second, "Timeout on remote-write requests.")
+	pflag.StringVar(&o.Username, "remote-write.username", "", "Username for remote-write basic auth.")
+	pflag.StringVar(&o.Password, "remote-write.password", "", "Password for remote-write basic auth.")
+	pflag.StringVar(&o.BearerToken, "remote-write.bearer-token", "", "Bearer token for remote-write requests, takes precedence over basic auth.")
+	pflag.IntVar(&o.QueueCapacity, "remote-write.queue-capacity", 120, "Maximum number of unsent batches kept for retrying, the oldest batch is dropped when exceeded.")
+	pflag.IntVar(&o.MaxRetries, "remote-write.max-retries", 3, "Maximum number of retries for a failed batch in one send attempt.")
+	pflag.StringVar(&o.BufferDir, "remote-write.buffer-dir", "", "Directory to buffer unsent batches on disk so they survive restarts. Batches are only kept in memory when empty.")
+}
+
+// RemoteWriter 按照固定的间隔执行抓取，并通过 remote-write 协议(protobuf+snappy)将样本发送到指定地址。
+// 适用于 Prometheus 无法访问 Exporter，但 Exporter 可以访问 Prometheus 的场景。
+// 发送失败的批次会被放入有界队列中，在下一次发送时按照先后顺序重新发送。
+type RemoteWriter struct {
+	opts   *RemoteWriteOpts
+	client *http.Client
+	queue  remoteWriteQueue
+}
+
+// NewRemoteWriter 实例化 RemoteWriter
+func NewRemoteWriter(opts *RemoteWriteOpts) (*RemoteWriter, error) {
+	var queue remoteWriteQueue = &memoryQueue{capacity: opts.QueueCapacity}
+	if opts.BufferDir != "" {
+		dq, err := newDirQueue(opts.BufferDir, opts.QueueCapacity)
+		if err != nil {
+			return nil, err
+		}
+		queue = dq
+	}
+
+	return &RemoteWriter{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  queue,
+	}, nil
+}
+
+// Run 按照 Interval 的间隔从 gatherer 中获取 Metric 并发送，直到 ctx 被取消
+func (w *RemoteWriter) Run(ctx context.Context, gatherer prometheus.Gatherer) {
+	logrus.WithField("url", w.opts.URL).Info("开始通过 remote-write 发送 Metric")
+	Poll(ctx, gatherer, w.opts.Interval, "remote-write", w.Write)
+}
+
+// Write 将 Metric 编码为一个批次放入队列，然后按照先后顺序发送队列中的所有批次
+func (w *RemoteWriter) Write(ctx context.Context, mfs []*dto.MetricFamily, ts time.Time) error {
+	batch := snappy.Encode(nil, encodeWriteRequest(mfs, ts))
+	if err := w.queue.push(batch); err != nil {
+		return err
+	}
+	return w.flush(ctx)
+}
+
+// flush 按照先后顺序发送队列中的批次，遇到无法发送的批次时停止，等待下一次发送
+func (w *RemoteWriter) flush(ctx context.Context) error {
+	for {
+		batch, ok, err := w.queue.peek()
+		if !ok {
+			if err != nil {
+				// 无法读取的批次(e.g. 文件损坏)直接丢弃，防止阻塞后续的批次
+				logrus.WithField("url", w.opts.URL).Errorf("丢弃无法读取的批次: %v", err)
+				if err := w.queue.pop(); err != nil {
+					return err
+				}
+				continue
+			}
+			return nil
+		}
+
+		if err := w.sendWithRetry(ctx, batch); err != nil {
+			if _, recoverable := err.(recoverableError); recoverable {
+				return fmt.Errorf("%v, %v batches queued for retry", err, w.queue.len())
+			}
+			// 不可恢复的错误(e.g. 数据格式错误)重试也不会成功，直接丢弃该批次
+			logrus.WithField("url", w.opts.URL).Errorf("丢弃无法发送的批次: %v", err)
+		}
+		if err := w.queue.pop(); err != nil {
+			return err
+		}
+	}
+}
+
+// remoteWriteBackoff 批次发送失败后第一次重试前等待的时间，之后每次重试翻倍
+var remoteWriteBackoff = time.Second
+
+// sendWithRetry 发送一个批次，出现可恢复的错误时按照指数退避进行重试
+func (w *RemoteWriter) sendWithRetry(ctx context.Context, batch []byte) (err error) {
+	backoff := remoteWriteBackoff
+	for i := 0; ; i++ {
+		if err = w.send(ctx, batch); err == nil {
+			return nil
+		}
+		if _, recoverable := err.(recoverableError); !recoverable || i >= w.opts.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return recoverableError{ctx.Err()}
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send 发送一个批次
+func (w *RemoteWriter) send(ctx context.Context, batch []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	switch {
+	case w.opts.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+w.opts.BearerToken)
+	case w.opts.Username != "":
+		req.SetBasicAuth(w.opts.Username, w.opts.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("error sending remote-write request http-statuscode: %s, body: %s", resp.Status, respBody)
+	// 与 Prometheus 一样，5xx 与 429 认为是可恢复的错误
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
+
+// recoverableError 表示重试后可能成功的错误
+type recoverableError struct {
+	error
+}
+
+// encodeWriteRequest 将 MetricFamily 编码为 remote-write 协议中的 WriteRequest。
+// 仅需要 TimeSeries 中的少量字段，所以直接使用 protowire 编码，而不是引入 Prometheus 中的 prompb。
+func encodeWriteRequest(mfs []*dto.MetricFamily, ts time.Time) []byte {
+	var buf []byte
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			var value float64
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				value = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			timestamp := ts.UnixMilli()
+			if m.TimestampMs != nil {
+				timestamp = m.GetTimestampMs()
+			}
+
+			labels := labelPairsToMap(m.Label)
+			labels[metricNameLabel] = mf.GetName()
+			// WriteRequest.timeseries = 1
+			buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+			buf = protowire.AppendBytes(buf, encodeTimeSeries(labels, value, timestamp))
+		}
+	}
+	return buf
+}
+
+// encodeTimeSeries 编码只包含一个样本的 TimeSeries，标签按照名称排序
+func encodeTimeSeries(labels map[string]string, value float64, timestamp int64) []byte {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf []byte
+	for _, name := range names {
+		// Label.name = 1, Label.value = 2
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, labels[name])
+		// TimeSeries.labels = 1
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, label)
+	}
+
+	// Sample.value = 1, Sample.timestamp = 2
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp))
+	// TimeSeries.samples = 2
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, sample)
+	return buf
+}
+
+// remoteWriteQueue 缓存待发送批次的有界队列，超过容量时丢弃最早的批次
+type remoteWriteQueue interface {
+	push(batch []byte) error
+	// peek 获取最早的批次，队列为空时返回 false
+	peek() ([]byte, bool, error)
+	// pop 删除最早的批次
+	pop() error
+	len() int
+}
+
+// memoryQueue 将批次缓存在内存中
+type memoryQueue struct {
+	mu       sync.Mutex
+	capacity int
+	batches  [][]byte
+}
+
+func (q *memoryQueue) push(batch []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.batches = append(q.batches, batch)
+	if q.capacity > 0 && len(q.batches) > q.capacity {
+		logrus.Warnf("remote-write 队列已满，丢弃 %v 个最早的批次", len(q.batches)-q.capacity)
+		q.batches = q.batches[len(q.batches)-q.capacity:]
+	}
+	return nil
+}
+
+func (q *memoryQueue) peek() ([]byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.batches) == 0 {
+		return nil, false, nil
+	}
+	return q.batches[0], true, nil
+}
+
+func (q *memoryQueue) pop() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.batches) > 0 {
+		q.batches = q.batches[1:]
+	}
+	return nil
+}
+
+func (q *memoryQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.batches)
+}
+
+// dirQueue 将批次缓存在目录中，每个批次一个文件，文件名为批次的序号，以便程序重启后继续发送
+type dirQueue struct {
+	mu       sync.Mutex
+	dir      string
+	capacity int
+	// files 按照先后顺序排列的批次文件名
+	files []string
+	next  uint64
+}
+
+// batchFileSuffix 批次文件的后缀
+const batchFileSuffix = ".snappy"
+
+// newDirQueue 实例化 dirQueue，并加载目录中已有的批次
+func newDirQueue(dir string, capacity int) (*dirQueue, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &dirQueue{dir: dir, capacity: capacity}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, batchFileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, batchFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		q.files = append(q.files, name)
+		if seq >= q.next {
+			q.next = seq + 1
+		}
+	}
+	// 文件名为定长的序号，按照字符串排序即为先后顺序
+	sort.Strings(q.files)
+	if len(q.files) > 0 {
+		logrus.WithField("dir", dir).Infof("加载了 %v 个未发送的 remote-write 批次", len(q.files))
+	}
+	return q, nil
+}
+
+func (q *dirQueue) push(batch []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	name := fmt.Sprintf("%020d%s", q.next, batchFileSuffix)
+	// 先写入临时文件再重命名，防止程序退出时留下不完整的批次
+	tmp := filepath.Join(q.dir, name+".tmp")
+	if err := os.WriteFile(tmp, batch, 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, name)); err != nil {
+		return err
+	}
+	q.next++
+	q.files = append(q.files, name)
+
+	for q.capacity > 0 && len(q.files) > q.capacity {
+		logrus.Warnf("remote-write 队列已满，丢弃最早的批次 %s", q.files[0])
+		if err := os.Remove(filepath.Join(q.dir, q.files[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		q.files = q.files[1:]
+	}
+	return nil
+}
+
+func (q *dirQueue) peek() ([]byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.files) == 0 {
+		return nil, false, nil
+	}
+	batch, err := os.ReadFile(filepath.Join(q.dir, q.files[0]))
+	return batch, err == nil, err
+}
+
+func (q *dirQueue) pop() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.files) == 0 {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(q.dir, q.files[0])); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	q.files = q.files[1:]
+	return nil
+}
+
+func (q *dirQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.files)
+}
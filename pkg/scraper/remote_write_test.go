@@ -0,0 +1,392 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// writeSample 是从 WriteRequest 中解码出的一个样本
+type writeSample struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest 按照 prompb 中 WriteRequest 的定义解码 protobuf，同时检查标签是否按照名称排序
+func decodeWriteRequest(t *testing.T, b []byte) []writeSample {
+	t.Helper()
+	var samples []writeSample
+	forEachField(t, b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) {
+		if num != 1 || typ != protowire.BytesType {
+			t.Fatalf("unexpected WriteRequest field %d", num)
+		}
+		s := writeSample{labels: map[string]string{}}
+		var names []string
+		forEachField(t, v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) {
+			switch num {
+			case 1:
+				var name, value string
+				forEachField(t, v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				names = append(names, name)
+				s.labels[name] = value
+			case 2:
+				forEachField(t, v, func(num protowire.Number, _ protowire.Type, _ []byte, n uint64) {
+					if num == 1 {
+						s.value = math.Float64frombits(n)
+					} else {
+						s.timestamp = int64(n)
+					}
+				})
+			}
+		})
+		if !sort.StringsAreSorted(names) {
+			t.Errorf("labels %v are not sorted by name", names)
+		}
+		samples = append(samples, s)
+	})
+	return samples
+}
+
+// forEachField 遍历 protobuf 消息中的字段，BytesType 的值通过 v 传递，Fixed64Type 与 VarintType 的值通过 n 传递
+func forEachField(t *testing.T, b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(l))
+		}
+		b = b[l:]
+		switch typ {
+		case protowire.BytesType:
+			v, l := protowire.ConsumeBytes(b)
+			if l < 0 {
+				t.Fatalf("invalid bytes: %v", protowire.ParseError(l))
+			}
+			fn(num, typ, v, 0)
+			b = b[l:]
+		case protowire.Fixed64Type:
+			n, l := protowire.ConsumeFixed64(b)
+			if l < 0 {
+				t.Fatalf("invalid fixed64: %v", protowire.ParseError(l))
+			}
+			fn(num, typ, nil, n)
+			b = b[l:]
+		case protowire.VarintType:
+			n, l := protowire.ConsumeVarint(b)
+			if l < 0 {
+				t.Fatalf("invalid varint: %v", protowire.ParseError(l))
+			}
+			fn(num, typ, nil, n)
+			b = b[l:]
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+	}
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_pool_used_bytes", Help: "Pool used bytes."}, []string{"pool", "cluster"})
+	gauge.WithLabelValues("p1", "c1").Set(42)
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests."})
+	counter.Add(3)
+	// remote-write 暂不发送 Histogram
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_latency_seconds", Help: "Latency."})
+	histogram.Observe(1)
+	reg.MustRegister(gauge, counter, histogram, timestampCollector{desc: prometheus.NewDesc("test_sample", "Test sample.", nil, nil)})
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1700000100, 0)
+	got := decodeWriteRequest(t, encodeWriteRequest(mfs, ts))
+	want := []writeSample{
+		{labels: map[string]string{metricNameLabel: "test_pool_used_bytes", "cluster": "c1", "pool": "p1"}, value: 42, timestamp: ts.UnixMilli()},
+		{labels: map[string]string{metricNameLabel: "test_requests_total"}, value: 3, timestamp: ts.UnixMilli()},
+		// 带有上游时间戳的 Metric 使用该时间戳
+		{labels: map[string]string{metricNameLabel: "test_sample"}, value: 1, timestamp: time.Unix(1700000000, 0).UnixMilli()},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("encodeWriteRequest() = %+v, want %+v", got, want)
+	}
+}
+
+// remoteWriteServer 是测试使用的 remote-write 接收端，按顺序使用 statuses 中的状态码响应请求，用完后响应 204
+type remoteWriteServer struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	// received 记录成功接收的样本
+	received []writeSample
+}
+
+func (s *remoteWriteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		s.t.Errorf("unexpected headers %v", r.Header)
+	}
+	compressed, _ := io.ReadAll(r.Body)
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		s.t.Errorf("snappy.Decode() = %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	status := http.StatusNoContent
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	if status/100 == 2 {
+		s.received = append(s.received, decodeWriteRequest(s.t, body)...)
+	}
+	w.WriteHeader(status)
+}
+
+// sampleValues 返回接收到的每个样本的值
+func (s *remoteWriteServer) sampleValues() []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var values []float64
+	for _, sample := range s.received {
+		values = append(values, sample.value)
+	}
+	return values
+}
+
+// gaugeFamilies 返回只包含一个值为 value 的 Gauge 的 MetricFamily
+func gaugeFamilies(value float64) []*dto.MetricFamily {
+	return []*dto.MetricFamily{{
+		Name:   stringPtr("test_value"),
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: &value}}},
+	}}
+}
+
+func stringPtr(s string) *string { return &s }
+
+// withFastBackoff 在测试期间缩短重试的等待时间
+func withFastBackoff(t *testing.T) {
+	backoff := remoteWriteBackoff
+	remoteWriteBackoff = time.Millisecond
+	t.Cleanup(func() { remoteWriteBackoff = backoff })
+}
+
+func TestRemoteWriterRetry(t *testing.T) {
+	withFastBackoff(t)
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		wantErr    bool
+		requests   int
+		received   []float64
+		queued     int
+	}{
+		{name: "success", requests: 1, received: []float64{1}},
+		{name: "5xx is retried", statuses: []int{500, 503}, maxRetries: 3, requests: 3, received: []float64{1}},
+		{name: "429 is retried", statuses: []int{429}, maxRetries: 3, requests: 2, received: []float64{1}},
+		{name: "4xx is dropped without retry", statuses: []int{400}, maxRetries: 3, requests: 1},
+		{name: "retries exhausted keep the batch queued", statuses: []int{500, 500, 500}, maxRetries: 2, wantErr: true, requests: 3, queued: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &remoteWriteServer{t: t, statuses: tt.statuses}
+			srv := httptest.NewServer(rs)
+			defer srv.Close()
+
+			w, err := NewRemoteWriter(&RemoteWriteOpts{URL: srv.URL, Timeout: time.Second, MaxRetries: tt.maxRetries})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = w.Write(context.Background(), gaugeFamilies(1), time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Write() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rs.requests != tt.requests {
+				t.Errorf("got %d requests, want %d", rs.requests, tt.requests)
+			}
+			if got := rs.sampleValues(); !reflect.DeepEqual(got, tt.received) {
+				t.Errorf("received %v, want %v", got, tt.received)
+			}
+			if w.queue.len() != tt.queued {
+				t.Errorf("%d batches queued, want %d", w.queue.len(), tt.queued)
+			}
+		})
+	}
+}
+
+// TestRemoteWriterQueue 检查发送失败的批次在下一次发送时按照先后顺序重新发送，超过容量时丢弃最早的批次
+func TestRemoteWriterQueue(t *testing.T) {
+	withFastBackoff(t)
+	rs := &remoteWriteServer{t: t, statuses: []int{500, 500, 500}}
+	srv := httptest.NewServer(rs)
+	defer srv.Close()
+
+	w, err := NewRemoteWriter(&RemoteWriteOpts{URL: srv.URL, Timeout: time.Second, QueueCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := w.Write(context.Background(), gaugeFamilies(float64(i)), time.Now()); err == nil {
+			t.Fatalf("Write(%d) = nil, want error", i)
+		}
+	}
+	if err := w.Write(context.Background(), gaugeFamilies(4), time.Now()); err != nil {
+		t.Fatalf("Write(4) = %v", err)
+	}
+	if got, want := rs.sampleValues(), []float64{3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+}
+
+func TestDirQueue(t *testing.T) {
+	dir := t.TempDir()
+	// 与批次无关的文件以及未写入完成的临时文件被忽略
+	os.WriteFile(filepath.Join(dir, "README"), []byte("x"), 0o640)
+	os.WriteFile(filepath.Join(dir, fmt.Sprintf("%020d%s.tmp", 7, batchFileSuffix)), []byte("x"), 0o640)
+
+	q, err := newDirQueue(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []string{"a", "b", "c", "d"} {
+		if err := q.push([]byte(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 超过容量时最早的批次被删除
+	assertQueue(t, q, "b", 3)
+	if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("%020d%s", 0, batchFileSuffix))); !os.IsNotExist(err) {
+		t.Errorf("dropped batch file still exists: %v", err)
+	}
+
+	// 重启后从目录中加载未发送的批次
+	q, err = newDirQueue(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertQueue(t, q, "b", 3)
+	if err := q.pop(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = newDirQueue(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertQueue(t, q, "c", 2)
+	// 重启后新批次的序号接在已有批次之后
+	if err := q.push([]byte("e")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"c", "d", "e"} {
+		assertQueue(t, q, want, q.len())
+		if err := q.pop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok, _ := q.peek(); ok {
+		t.Error("peek() on empty queue = true")
+	}
+}
+
+// assertQueue 检查队列中最早的批次与批次数量
+func assertQueue(t *testing.T, q *dirQueue, first string, n int) {
+	t.Helper()
+	batch, ok, err := q.peek()
+	if !ok || err != nil || string(batch) != first {
+		t.Errorf("peek() = %q, %v, %v, want %q", batch, ok, err, first)
+	}
+	if q.len() != n {
+		t.Errorf("len() = %d, want %d", q.len(), n)
+	}
+}
+
+// TestRemoteWriterReplay 检查设置了 BufferDir 时，程序重启后会继续发送之前未发送成功的批次
+func TestRemoteWriterReplay(t *testing.T) {
+	withFastBackoff(t)
+	dir := t.TempDir()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	w, err := NewRemoteWriter(&RemoteWriteOpts{URL: down.URL, Timeout: time.Second, BufferDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		if err := w.Write(context.Background(), gaugeFamilies(float64(i)), time.Now()); err == nil {
+			t.Fatalf("Write(%d) = nil, want error", i)
+		}
+	}
+	down.Close()
+
+	rs := &remoteWriteServer{t: t}
+	srv := httptest.NewServer(rs)
+	defer srv.Close()
+	w, err = NewRemoteWriter(&RemoteWriteOpts{URL: srv.URL, Timeout: time.Second, BufferDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(context.Background(), gaugeFamilies(3), time.Now()); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if got, want := rs.sampleValues(), []float64{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("%d files left in buffer dir after all batches were sent", len(entries))
+	}
+}
+
+// TestRemoteWriterCorruptBatch 检查无法读取的批次被丢弃，不会阻塞后续的批次
+func TestRemoteWriterCorruptBatch(t *testing.T) {
+	dir := t.TempDir()
+	// 目录无法作为批次读取
+	if err := os.Mkdir(filepath.Join(dir, fmt.Sprintf("%020d%s", 0, batchFileSuffix)), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	q, err := newDirQueue(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.files = append(q.files, fmt.Sprintf("%020d%s", 0, batchFileSuffix))
+	q.next = 1
+
+	rs := &remoteWriteServer{t: t}
+	srv := httptest.NewServer(rs)
+	defer srv.Close()
+	w := &RemoteWriter{opts: &RemoteWriteOpts{URL: srv.URL}, client: srv.Client(), queue: q}
+	if err := w.Write(context.Background(), gaugeFamilies(1), time.Now()); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if got, want := rs.sampleValues(), []float64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+}
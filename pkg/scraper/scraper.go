@@ -23,6 +23,16 @@ type CommonScraper interface {
 	Scrape(client CommonClient, ch chan<- prometheus.Metric) error
 }
 
+// DescribableScraper 是 CommonScraper 的可选扩展。
+// 实现了该接口的 Scraper 可以声明其会产生的所有 Metric 的 Desc，以便生成指标目录。
+// Desc 应该通过 NewDesc 创建，否则无法获取 Metric 的类型等信息。
+type DescribableScraper interface {
+	CommonScraper
+
+	// Describe 将该 Scraper 会产生的所有 Metric 的 Desc 发送到 channel(通道) 中
+	Describe(ch chan<- *prometheus.Desc)
+}
+
 // CommonClient 是连接 Server 的客户端接口，不同的 Server，客户端的信息不同。但是至少需要两种行为
 // 第一:根据给定的 API 与 Server 建立连接，并获取响应体
 // 第二:判断 Server 是否存活
@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeClient 是测试使用的 CommonClient，responses 的 key 为 endpoint，value 为响应体
+type fakeClient struct {
+	responses map[string]string
+	pingErr   error
+}
+
+func (c fakeClient) Request(method string, endpoint string, reqBody io.Reader) ([]byte, error) {
+	body, ok := c.responses[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("unexpected request %s %s", method, endpoint)
+	}
+	return []byte(body), nil
+}
+
+func (c fakeClient) Ping() (bool, error) { return c.pingErr == nil, c.pingErr }
+
+func (c fakeClient) GetConcurrency() int { return 1 }
+
+// fakeScraper 是测试使用的 CommonScraper，每次抓取都调用 scrape 发送 Metric
+type fakeScraper struct {
+	name   string
+	scrape func(ch chan<- prometheus.Metric)
+	err    error
+}
+
+func (s fakeScraper) Name() string { return s.name }
+
+func (s fakeScraper) Help() string { return "Fake scraper for tests" }
+
+func (s fakeScraper) Scrape(client CommonClient, ch chan<- prometheus.Metric) error {
+	if s.scrape != nil {
+		s.scrape(ch)
+	}
+	return s.err
+}
+
+// gather 注册 c 并获取一次所有 Metric，返回以 Metric 名称为 key 的 MetricFamily
+func gather(t *testing.T, c prometheus.Collector) map[string]*dto.MetricFamily {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() = %v", err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(mfs))
+	for _, mf := range mfs {
+		byName[mf.GetName()] = mf
+	}
+	return byName
+}
+
+// labelsOf 返回 Metric 的标签
+func labelsOf(m *dto.Metric) map[string]string {
+	return labelPairsToMap(m.Label)
+}
@@ -0,0 +1,209 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/coreos/go-systemd/daemon"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// Server 包含了运行一个 Exporter 所需的全部逻辑：命令行标志、单目标与多目标模式、各种推送模式、健康检查、状态页面与调试接口。
+// 每个 Exporter 的 main 只需要提供 Scraper 以及创建客户端的方法，然后依次调用 AddFlag、pflag.Parse 与 Run
+type Server struct {
+	// Name Exporter 的名称，作为 Trace 与 OTLP 的服务名、Pushgateway 默认的 job 以及状态页面的标题
+	Name string
+	// Scrapers 所有 Scraper 以及默认情况下是否开启
+	Scrapers map[CommonScraper]bool
+	// NewClient 创建连接目标的客户端，target 为 nil 时表示使用命令行标志中的目标
+	NewClient func(target *TargetConfig) (CommonClient, error)
+
+	listenAddress   string
+	metricsPath     string
+	printMetrics    string
+	exporterOpts    ExporterOpts
+	otlpOpts        OTLPOpts
+	remoteWriteOpts RemoteWriteOpts
+	pushOpts        PushgatewayOpts
+	tracingOpts     TracingOpts
+	healthOpts      HealthOpts
+	debugOpts       DebugOpts
+	// scraperFlags 记录每个 Scraper 的 --collect.XXX 标志的值
+	scraperFlags map[CommonScraper]*bool
+}
+
+// AddFlag 设置运行 Exporter 所需的所有命令行标志，listenAddress 为默认的监听地址
+func (s *Server) AddFlag(listenAddress string) {
+	pflag.StringVar(&s.listenAddress, "web.listen-address", listenAddress, "Address to listen on for web interface and telemetry.")
+	pflag.StringVar(&s.metricsPath, "web.telemetry-path", "/metrics", "Path under which to expose metrics.")
+	pflag.StringVar(&s.printMetrics, "print-metrics", "", "Print the catalog of all metrics this exporter can produce in the given format (markdown or json) and exit.")
+
+	// 设置 Exporter 通用选项的标志
+	s.exporterOpts.AddFlag()
+	// 设置通过 OTLP 推送 Metric 的标志
+	s.otlpOpts.AddFlag()
+	// 设置通过 remote-write 发送 Metric 的标志
+	s.remoteWriteOpts.AddFlag()
+	// 设置推送到 Pushgateway 的标志
+	s.pushOpts.AddFlag()
+	// 设置通过 OTLP 导出 Trace 的标志
+	s.tracingOpts.AddFlag()
+	// 设置健康检查与就绪检查的标志
+	s.healthOpts.AddFlag()
+	// 设置调试接口的标志
+	s.debugOpts.AddFlag()
+
+	// 生成抓取器的命令行标志，用于通过命令行控制开启哪些抓取器，说白了就是控制采集哪些指标。
+	// 命令行标志的值放到 scraperFlags 中，Run 时只有值为 true 的抓取器才会注册
+	s.scraperFlags = make(map[CommonScraper]*bool, len(s.Scrapers))
+	for scraper, enabledByDefault := range s.Scrapers {
+		s.scraperFlags[scraper] = pflag.Bool("collect."+scraper.Name(), enabledByDefault, scraper.Help())
+	}
+}
+
+// allScrapers 返回所有 Scraper，无论是否开启
+func (s *Server) allScrapers() []CommonScraper {
+	all := make([]CommonScraper, 0, len(s.Scrapers))
+	for scraper := range s.Scrapers {
+		all = append(all, scraper)
+	}
+	return all
+}
+
+// Run 在解析命令行标志之后调用，启动 Exporter 并阻塞直到 HTTP 服务退出。url 为命令行标志中的目标地址，未配置多目标时作为目标的名称。
+// 设置了 --print-metrics 时输出指标目录后返回；设置了 Pushgateway 地址时只执行一次抓取并推送，通过退出码反映结果。
+// 与 main 一样，无法启动时直接退出进程
+func (s *Server) Run(url string) {
+	// 输出指标目录后直接返回，不需要连接目标
+	if s.printMetrics != "" {
+		if err := WriteCatalog(os.Stdout, s.printMetrics, NewCatalog(s.allScrapers())); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+
+	// 加载 Exporter 的配置文件
+	if err := s.exporterOpts.LoadConfig(); err != nil {
+		logrus.Fatal("加载配置文件失败 ", err)
+	}
+	if err := s.debugOpts.Validate(); err != nil {
+		logrus.Fatal(err)
+	}
+
+	// 多目标模式中每个 Metric 都带有 target 标签，不再使用命令行标志中的目标
+	target := url
+	if len(s.exporterOpts.Config.Targets) > 0 {
+		target = ""
+	}
+
+	// 设置了 Trace 导出地址时，每次抓取都会生成一个 Trace，否则所有 Span 都是 no-op 的
+	shutdownTracing, err := InitTracing(context.Background(), &s.tracingOpts, s.Name, target)
+	if err != nil {
+		logrus.Fatal("初始化 Trace 失败 ", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// ######## 下面的都是 Exporter 运行的最主要逻辑了 ########
+	// 获取所有通过命令行标志，设置开启的 scrapers(抓取器)。
+	enabledScrapers := []CommonScraper{}
+	for scraper, enabled := range s.scraperFlags {
+		if *enabled {
+			logrus.Info("Scraper enabled ", scraper.Name())
+			enabledScrapers = append(enabledScrapers, scraper)
+		}
+	}
+	// 使用配置文件中每个 Scraper 自己的配置，e.g. 过滤器。为不支持过滤器的 Scraper 配置过滤器时会启动失败
+	enabledScrapers, err = ConfigureScrapers(enabledScrapers, s.exporterOpts.Config)
+	if err != nil {
+		logrus.Fatal("配置 Scraper 失败 ", err)
+	}
+	// 实例化 Exporter，NewExporter 的两个参数分别用来传递 连接目标的客户端 以及 需要采集的Metrics。
+	// 配置文件中设置了 targets 时，每个目标都有自己的客户端与 Exporter，所有目标共享同一个并发限制
+	targets := NewTargets(s.exporterOpts.TargetConcurrency)
+	if len(s.exporterOpts.Config.Targets) == 0 {
+		// 客户端实例化时只校验地址，目标无法连接时不会退出，而是在抓取时体现在 up 上
+		client, err := s.NewClient(nil)
+		if err != nil {
+			logrus.Fatal("初始化客户端失败 ", err)
+		}
+		targets.Add(url, NewExporter(client, enabledScrapers, &s.exporterOpts))
+	}
+	for _, t := range s.exporterOpts.Config.Targets {
+		logrus.Info("Target enabled ", t.TargetName())
+		client, err := s.NewClient(t)
+		if err != nil {
+			logrus.Fatalf("初始化目标 %s 的客户端失败 %v", t.TargetName(), err)
+		}
+		targets.Add(t.TargetName(), NewExporter(client, enabledScrapers, s.exporterOpts.ForTarget(t)))
+	}
+	// 实例化一个注册器,并使用这个注册器注册所有目标
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(targets)
+	// 设置了 Pushgateway 地址时，只执行一次抓取并推送，通过退出码反映抓取结果，适合通过 cron 运行
+	if s.pushOpts.URL != "" {
+		if s.pushOpts.Job == "" {
+			s.pushOpts.Job = s.Name
+		}
+		var grouping map[string]string
+		if target != "" {
+			grouping = map[string]string{TargetLabel: target}
+		}
+		scrapeOK, err := PushOnce(&s.pushOpts, reg, grouping)
+		if err != nil {
+			logrus.Error("推送到 Pushgateway 失败 ", err)
+			os.Exit(2)
+		}
+		if !scrapeOK {
+			os.Exit(1)
+		}
+		return
+	}
+	// 设置了 OTLP 地址时，在后台定期将 Metric 推送到 OpenTelemetry Collector
+	if s.otlpOpts.Endpoint != "" {
+		go NewOTLPPusher(&s.otlpOpts, s.Name, target).Run(context.Background(), reg)
+	}
+	// 设置了 remote-write 地址时，在后台定期将 Metric 发送到 Prometheus
+	if s.remoteWriteOpts.URL != "" {
+		remoteWriter, err := NewRemoteWriter(&s.remoteWriteOpts)
+		if err != nil {
+			logrus.Fatal("初始化 remote-write 失败 ", err)
+		}
+		go remoteWriter.Run(context.Background(), reg)
+	}
+	// 在后台定期检查目标是否可以连接，作为 /-/ready 的依据
+	health := NewHealth(&s.healthOpts, targets)
+	go health.Run(context.Background())
+	// ######## Exporter 主要运行逻辑结束 ########
+
+	// ######## 设置路由信息 ########
+	// 首页展示 Exporter 与每个 Scraper 的状态，/api/v1/status 以 JSON 格式提供相同的内容
+	statusInfo := StatusInfo{
+		Name:        s.Name,
+		MetricsPath: s.metricsPath,
+		Scrapers:    s.allScrapers(),
+		Health:      health,
+	}
+	http.Handle("/", targets.StatusHandler(statusInfo))
+	http.Handle("/api/v1/status", targets.StatusAPIHandler(statusInfo))
+	// 每个 /metrics 请求都会生成一个 Trace，其中包含每个 Scraper 以及对目标的每次请求的子 Span
+	http.Handle(s.metricsPath, NewMetricsHandler(targets, promhttp.HandlerOpts{ErrorLog: logrus.StandardLogger()}))
+	// /-/healthy 表示进程存活，/-/ready 表示可以正常抓取目标，二者都返回 JSON 格式的详细信息
+	http.Handle("/-/healthy", health.HealthyHandler())
+	http.Handle("/-/ready", health.ReadyHandler())
+	// 调试接口会执行指定的 Scraper 并返回目标的原始响应，只有显式开启时才注册
+	if s.debugOpts.Enabled {
+		http.Handle("/debug/scrapers/", targets.DebugHandler(&s.debugOpts))
+	}
+	// ######## 设置路由信息结束 ########
+
+	// 启动前检查并启动 Exporter
+	logrus.Info("Listening on address ", s.listenAddress)
+	daemon.SdNotify(false, daemon.SdNotifyReady)
+	if err := http.ListenAndServe(s.listenAddress, nil); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -0,0 +1,279 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/version"
+	"github.com/sirupsen/logrus"
+)
+
+// recentScrapes 计算成功率时使用的最近抓取次数
+const recentScrapes = 20
+
+// targetStats 记录目标最近一次 Ping 的情况
+type targetStats struct {
+	lastPing  time.Time
+	lastError string
+}
+
+// scraperStats 记录一个 Scraper 最近的抓取情况
+type scraperStats struct {
+	lastScrape    time.Time
+	lastDuration  time.Duration
+	lastError     string
+	lastErrorTime time.Time
+	// recent 最近 recentScrapes 次抓取是否成功，按照时间顺序排列
+	recent []bool
+}
+
+// recordPing 记录一次 Ping 的结果
+func (e *Exporter) recordPing(err error) {
+	e.statsMutex.Lock()
+	defer e.statsMutex.Unlock()
+	e.targetStats.lastPing = time.Now()
+	e.targetStats.lastError = ""
+	if err != nil {
+		e.targetStats.lastError = err.Error()
+	}
+}
+
+// recordScrape 记录一次 Scraper 的抓取结果
+func (e *Exporter) recordScrape(name string, duration time.Duration, err error) {
+	e.statsMutex.Lock()
+	defer e.statsMutex.Unlock()
+
+	stats, ok := e.scraperStats[name]
+	if !ok {
+		stats = &scraperStats{}
+		e.scraperStats[name] = stats
+	}
+	stats.lastScrape = time.Now()
+	stats.lastDuration = duration
+	if err != nil {
+		stats.lastError = err.Error()
+		stats.lastErrorTime = stats.lastScrape
+	}
+	stats.recent = append(stats.recent, err == nil)
+	if len(stats.recent) > recentScrapes {
+		stats.recent = stats.recent[len(stats.recent)-recentScrapes:]
+	}
+}
+
+// TokenStatus 认证 Token 的状态，不包含 Token 本身
+type TokenStatus struct {
+	Present   bool       `json:"present"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// TokenReporter 是可以报告认证 Token 状态的 CommonClient，状态页面通过它展示 Token 的状态
+type TokenReporter interface {
+	TokenStatus() TokenStatus
+}
+
+// StatusInfo 状态页面中与 Exporter 运行状态无关的信息
+type StatusInfo struct {
+	// Name Exporter 的名称
+	Name string
+	// MetricsPath 暴露 Metric 的路径
+	MetricsPath string
+	// Scrapers 所有可用的 Scraper，包括未启用的
+	Scrapers []CommonScraper
+	// Health 为 nil 时状态中不包含就绪检查的结果
+	Health *Health
+}
+
+// Status 状态页面与 JSON API 的内容
+type Status struct {
+	Name        string         `json:"name"`
+	Version     VersionStatus  `json:"version"`
+	MetricsPath string         `json:"metrics_path"`
+	Ready       *HealthStatus  `json:"ready,omitempty"`
+	Targets     []TargetStatus `json:"targets"`
+}
+
+// VersionStatus 构建信息
+type VersionStatus struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// TargetStatus 一个抓取目标的状态
+type TargetStatus struct {
+	Target    string          `json:"target"`
+	LastPing  *time.Time      `json:"last_ping,omitempty"`
+	PingError string          `json:"ping_error,omitempty"`
+	Token     *TokenStatus    `json:"token,omitempty"`
+	Scrapers  []ScraperStatus `json:"scrapers"`
+}
+
+// ScraperStatus 一个 Scraper 的状态
+type ScraperStatus struct {
+	Name                string     `json:"name"`
+	Help                string     `json:"help"`
+	Enabled             bool       `json:"enabled"`
+	LastScrape          *time.Time `json:"last_scrape,omitempty"`
+	LastDurationSeconds float64    `json:"last_duration_seconds"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorTime       *time.Time `json:"last_error_time,omitempty"`
+	// SuccessRate 最近 RecentScrapes 次抓取的成功率，从未抓取过时为 nil
+	SuccessRate   *float64 `json:"success_rate,omitempty"`
+	RecentScrapes int      `json:"recent_scrapes"`
+}
+
+// Status 根据所有目标当前记录的抓取情况生成状态信息
+func (t *Targets) Status(info StatusInfo) Status {
+	status := Status{
+		Name: info.Name,
+		Version: VersionStatus{
+			Version:   version.Version,
+			Revision:  version.GetRevision(),
+			Branch:    version.Branch,
+			BuildDate: version.BuildDate,
+			GoVersion: version.GoVersion,
+		},
+		MetricsPath: info.MetricsPath,
+		Targets:     make([]TargetStatus, 0, len(t.exporters)),
+	}
+	if info.Health != nil {
+		_, ready := info.Health.Ready()
+		status.Ready = &ready
+	}
+	for i, e := range t.exporters {
+		status.Targets = append(status.Targets, e.TargetStatus(t.names[i], info.Scrapers))
+	}
+	return status
+}
+
+// TargetStatus 根据 Exporter 当前记录的抓取情况生成目标的状态信息，scrapers 为所有可用的 Scraper，包括未启用的
+func (e *Exporter) TargetStatus(target string, scrapers []CommonScraper) TargetStatus {
+	status := TargetStatus{Target: target, Scrapers: []ScraperStatus{}}
+	if tr, ok := e.client.(TokenReporter); ok {
+		token := tr.TokenStatus()
+		status.Token = &token
+	}
+
+	enabled := make(map[string]bool, len(e.scrapers))
+	for _, s := range e.scrapers {
+		enabled[s.Name()] = true
+	}
+	// 没有提供所有可用的 Scraper 时，只展示已经启用的 Scraper
+	if len(scrapers) == 0 {
+		scrapers = e.scrapers
+	}
+
+	e.statsMutex.RLock()
+	defer e.statsMutex.RUnlock()
+
+	if !e.targetStats.lastPing.IsZero() {
+		lastPing := e.targetStats.lastPing
+		status.LastPing = &lastPing
+		status.PingError = e.targetStats.lastError
+	}
+	for _, s := range scrapers {
+		ss := ScraperStatus{Name: s.Name(), Help: s.Help(), Enabled: enabled[s.Name()]}
+		if stats, ok := e.scraperStats[s.Name()]; ok {
+			lastScrape := stats.lastScrape
+			ss.LastScrape = &lastScrape
+			ss.LastDurationSeconds = stats.lastDuration.Seconds()
+			ss.LastError = stats.lastError
+			if !stats.lastErrorTime.IsZero() {
+				lastErrorTime := stats.lastErrorTime
+				ss.LastErrorTime = &lastErrorTime
+			}
+			var succeeded int
+			for _, ok := range stats.recent {
+				if ok {
+					succeeded++
+				}
+			}
+			ss.RecentScrapes = len(stats.recent)
+			if ss.RecentScrapes > 0 {
+				rate := float64(succeeded) / float64(ss.RecentScrapes)
+				ss.SuccessRate = &rate
+			}
+		}
+		status.Scrapers = append(status.Scrapers, ss)
+	}
+	sort.Slice(status.Scrapers, func(i, j int) bool { return status.Scrapers[i].Name < status.Scrapers[j].Name })
+
+	return status
+}
+
+// StatusHandler 返回以 HTML 页面展示 Exporter 状态的 http.Handler
+func (t *Targets) StatusHandler(info StatusInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 注册在 / 上时会匹配所有未注册的路径
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statusTemplate.Execute(w, t.Status(info)); err != nil {
+			logrus.Errorf("渲染状态页面失败: %v", err)
+		}
+	})
+}
+
+// StatusAPIHandler 返回以 JSON 格式返回 Exporter 状态的 http.Handler，供自动化工具使用
+func (t *Targets) StatusAPIHandler(info StatusInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(t.Status(info)); err != nil {
+			logrus.Errorf("写入状态信息失败: %v", err)
+		}
+	})
+}
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"time": func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	},
+	"percent": func(f *float64) string {
+		if f == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", *f*100)
+	},
+}).Parse(`<html>
+<head><title>{{.Name}}</title></head>
+<body>
+<h1>{{.Name}}</h1>
+<p><a href='{{.MetricsPath}}'>Metrics</a> | <a href='/api/v1/status'>Status API</a></p>
+<table border="1" cellpadding="4">
+<tr><th>Version</th><td>{{.Version.Version}} (revision={{.Version.Revision}}, branch={{.Version.Branch}}, build_date={{.Version.BuildDate}}, go={{.Version.GoVersion}})</td></tr>
+{{- with .Ready}}
+<tr><th>Ready</th><td>{{.Status}}</td></tr>
+{{- end}}
+</table>
+{{- range .Targets}}
+<h2>Target: {{.Target}}</h2>
+<table border="1" cellpadding="4">
+<tr><th>Last ping</th><td>{{time .LastPing}}{{with .PingError}} ({{.}}){{end}}</td></tr>
+{{- with .Token}}
+<tr><th>Token</th><td>{{if .Present}}present, updated at {{time .UpdatedAt}}{{else}}missing{{end}}</td></tr>
+{{- end}}
+</table>
+<h3>Scrapers</h3>
+<table border="1" cellpadding="4">
+<tr><th>Name</th><th>Enabled</th><th>Last scrape</th><th>Last duration (s)</th><th>Success rate</th><th>Last error</th><th>Last error time</th></tr>
+{{- range .Scrapers}}
+<tr><td title="{{.Help}}">{{.Name}}</td><td>{{.Enabled}}</td><td>{{time .LastScrape}}</td><td>{{printf "%.3f" .LastDurationSeconds}}</td><td>{{percent .SuccessRate}} ({{.RecentScrapes}})</td><td>{{.LastError}}</td><td>{{time .LastErrorTime}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Targets 将多个抓取目标的 Exporter 组合在一起，实现了 prometheus.Collector 与 ContextCollector。
+// 每个目标都有自己的客户端与本程序默认自带的 Metrics(up 等)，所有目标并行抓取，并共享同一个并发限制。
+// 只有一个目标时，与直接使用该目标的 Exporter 相同
+type Targets struct {
+	names     []string
+	exporters []*Exporter
+	// sem 限制所有目标同时执行的 Scraper 数量，为 nil 时不限制
+	sem chan struct{}
+}
+
+// NewTargets 实例化 Targets，concurrency 为所有目标同时执行的 Scraper 的最大数量，0 表示不限制
+func NewTargets(concurrency int) *Targets {
+	t := &Targets{}
+	if concurrency > 0 {
+		t.sem = make(chan struct{}, concurrency)
+	}
+	return t
+}
+
+// Add 添加一个抓取目标，name 用于在状态页面与调试接口中区分目标
+func (t *Targets) Add(name string, e *Exporter) {
+	e.sem = t.sem
+	t.names = append(t.names, name)
+	t.exporters = append(t.exporters, e)
+}
+
+// Exporter 返回指定目标的 Exporter。name 为空并且只有一个目标时返回该目标
+func (t *Targets) Exporter(name string) (*Exporter, bool) {
+	if name == "" && len(t.exporters) == 1 {
+		return t.exporters[0], true
+	}
+	for i, n := range t.names {
+		if n == name {
+			return t.exporters[i], true
+		}
+	}
+	return nil, false
+}
+
+// Names 返回所有目标的名称
+func (t *Targets) Names() []string {
+	return append([]string(nil), t.names...)
+}
+
+// Describe 实现 Collector 接口的方法。
+// 只要有一个目标的 Exporter 作为 unchecked collector，所有目标都作为 unchecked collector
+func (t *Targets) Describe(ch chan<- *prometheus.Desc) {
+	var descs []*prometheus.Desc
+	for _, e := range t.exporters {
+		descCh := make(chan *prometheus.Desc)
+		go func() {
+			e.Describe(descCh)
+			close(descCh)
+		}()
+		n := len(descs)
+		for desc := range descCh {
+			descs = append(descs, desc)
+		}
+		if len(descs) == n {
+			return
+		}
+	}
+	for _, desc := range descs {
+		ch <- desc
+	}
+}
+
+// Collect 实现 Collector 接口的方法
+func (t *Targets) Collect(ch chan<- prometheus.Metric) {
+	t.CollectContext(context.Background(), ch)
+}
+
+// CollectContext 并行抓取所有目标。实现了 ContextCollector 接口
+func (t *Targets) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, e := range t.exporters {
+		wg.Add(1)
+		go func(e *Exporter) {
+			defer wg.Done()
+			e.CollectContext(ctx, ch)
+		}(e)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,51 @@
+package scraper
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// stripTimestamps 返回一个用于替代 ch 的通道，发送到该通道的 Metric 去掉时间戳后，再发送到 ch 中。
+// Scraper 可以通过 prometheus.NewMetricWithTimestamp 使用上游数据中的时间戳作为 Metric 的时间戳，
+// 只有在配置文件中为该 Scraper 设置了 honor_timestamps 时才会保留这些时间戳，否则与其他 Metric 一样使用抓取时间。
+// 所有 Metric 发送完成后需要调用返回的 done 函数
+func (e *Exporter) stripTimestamps(scraperName string, ch chan<- prometheus.Metric) (stripped chan<- prometheus.Metric, done func()) {
+	if e.opts.Config.HonorTimestampsFor(scraperName) {
+		return ch, func() {}
+	}
+
+	in := make(chan prometheus.Metric)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range in {
+			ch <- stripTimestamp(m)
+		}
+	}()
+
+	return in, func() {
+		close(in)
+		wg.Wait()
+	}
+}
+
+// stripTimestamp 去掉 Metric 的时间戳，没有时间戳或无法处理的 Metric 原样返回
+func stripTimestamp(m prometheus.Metric) prometheus.Metric {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil || pb.TimestampMs == nil {
+		return m
+	}
+	s, err := parseMetric(m)
+	if err != nil {
+		return m
+	}
+	s.timestamp = nil
+	nm, err := s.toMetric(m.Desc())
+	if err != nil {
+		return m
+	}
+	return nm
+}
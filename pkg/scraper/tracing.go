@@ -0,0 +1,194 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 本包中所有 Span 所属的 Tracer 名称
+const tracerName = "github.com/DesistDaydream/prometheus-instrumenting/pkg/scraper"
+
+// TracingOpts 通过 OTLP/HTTP 导出 Trace 所需的选项
+type TracingOpts struct {
+	// Endpoint OTLP/HTTP 接收 Trace 的地址，为空时不导出，所有 Span 都是 no-op 的，e.g. http://otel-collector:4318/v1/traces
+	Endpoint string
+	// SampleRatio 采样比例，取值范围为 0 到 1
+	SampleRatio float64
+	// Headers 导出时添加的请求头，格式为 key=value
+	Headers []string
+}
+
+// AddFlag use after set Opts
+func (o *TracingOpts) AddFlag() {
+	pflag.StringVar(&o.Endpoint, "otlp.traces-endpoint", "", "OTLP/HTTP endpoint to export traces to, e.g. http://localhost:4318/v1/traces. Tracing is disabled when empty.")
+	pflag.Float64Var(&o.SampleRatio, "otlp.traces-sample-ratio", 1, "Ratio of /metrics requests to trace, between 0 and 1.")
+	pflag.StringArrayVar(&o.Headers, "otlp.traces-header", nil, "Header added to OTLP trace export requests, in key=value format. May be repeated.")
+}
+
+// InitTracing 根据 opts 初始化全局的 TracerProvider。exporterName 与 target 将作为资源属性随 Trace 一起导出。
+// Endpoint 为空时不做任何操作。返回的 shutdown 用于在程序退出前导出剩余的 Span
+func InitTracing(ctx context.Context, opts *TracingOpts, exporterName string, target string) (shutdown func(context.Context) error, err error) {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	headers := make(map[string]string, len(opts.Headers))
+	for _, h := range opts.Headers {
+		k, v, ok := strings.Cut(h, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTLP header %q, must be in key=value format", h)
+		}
+		headers[k] = v
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(opts.Endpoint),
+		otlptracehttp.WithHeaders(headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 多目标模式中 target 为空，每个 Span 通过自己的属性区分目标
+	attrs := []attribute.KeyValue{attribute.String("service.name", exporterName)}
+	if target != "" {
+		attrs = append(attrs, attribute.String("target", target))
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	logrus.WithField("endpoint", opts.Endpoint).Info("开始通过 OTLP 导出 Trace")
+
+	return tp.Shutdown, nil
+}
+
+// Tracer 返回用于创建 Span 的 Tracer，未调用 InitTracing 时创建的 Span 都是 no-op 的
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Logger 返回带有 ctx 中 Trace 信息的日志条目，用于将日志与 Trace 关联起来
+func Logger(ctx context.Context) *logrus.Entry {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+	return logrus.WithFields(logrus.Fields{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	})
+}
+
+// EndSpan 根据 err 设置 Span 的状态并结束 Span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ContextClient 是可以接收 context 的 CommonClient。
+// Exporter 会通过 RequestWithContext 将 Trace 信息传递给每一次对上游的请求，以便为其创建子 Span
+type ContextClient interface {
+	CommonClient
+	RequestWithContext(ctx context.Context, method string, endpoint string, reqBody io.Reader) ([]byte, error)
+}
+
+// contextClient 将 ctx 与 CommonClient 绑定在一起，Scraper 调用 Request 时会使用绑定的 ctx
+type contextClient struct {
+	CommonClient
+	ctx context.Context
+}
+
+// withContext 返回绑定了 ctx 的 CommonClient，传递给 Scraper 使用
+func withContext(ctx context.Context, cc CommonClient) CommonClient {
+	return contextClient{CommonClient: cc, ctx: ctx}
+}
+
+// Request 实现 CommonClient 接口。未实现 ContextClient 接口的客户端由这里为每次请求创建 Span
+func (c contextClient) Request(method string, endpoint string, reqBody io.Reader) ([]byte, error) {
+	if cc, ok := c.CommonClient.(ContextClient); ok {
+		return cc.RequestWithContext(c.ctx, method, endpoint, reqBody)
+	}
+
+	_, span := Tracer().Start(c.ctx, method+" "+endpoint, trace.WithSpanKind(trace.SpanKindClient))
+	body, err := c.CommonClient.Request(method, endpoint, reqBody)
+	EndSpan(span, err)
+	return body, err
+}
+
+// ContextCollector 是可以接收 context 的 prometheus.Collector，Exporter 与 Targets 都实现了该接口
+type ContextCollector interface {
+	prometheus.Collector
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// NewMetricsHandler 返回暴露 c 中 Metric 的 http.Handler。
+// 每个请求都会创建一个 Trace，每个 Scraper 的执行以及对上游的每次请求都是其中的子 Span
+func NewMetricsHandler(c ContextCollector, opts promhttp.HandlerOpts) http.Handler {
+	// Desc 只需要获取一次，之后每个请求都使用相同的 Desc 注册
+	descCh := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(descCh)
+		close(descCh)
+	}()
+	var descs []*prometheus.Desc
+	for desc := range descCh {
+		descs = append(descs, desc)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, r.Method+" "+r.URL.Path, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		// Collect 无法接收 context，所以每个请求都使用一个新的注册器，注册绑定了 ctx 的 Collector
+		reg := prometheus.NewRegistry()
+		if err := reg.Register(boundCollector{c: c, ctx: ctx, descs: descs}); err != nil {
+			EndSpan(span, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		promhttp.HandlerFor(reg, opts).ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// boundCollector 将 ctx 与 ContextCollector 绑定在一起，实现 prometheus.Collector
+type boundCollector struct {
+	c     ContextCollector
+	ctx   context.Context
+	descs []*prometheus.Desc
+}
+
+// Describe 实现 Collector 接口的方法
+func (b boundCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range b.descs {
+		ch <- desc
+	}
+}
+
+// Collect 实现 Collector 接口的方法
+func (b boundCollector) Collect(ch chan<- prometheus.Metric) {
+	b.c.CollectContext(b.ctx, ch)
+}